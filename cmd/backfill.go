@@ -0,0 +1,60 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"log"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/redselig/currencier/internal/data/app"
+	"github.com/redselig/currencier/internal/util"
+)
+
+var backfillFrom, backfillTo string
+
+// backfillCmd represents the backfill command
+var backfillCmd = &cobra.Command{
+	Use:   "backfill",
+	Short: "import historical currencies",
+	Long:  `backfill loads currencies for every day of the date range and stores them to history`,
+	Run: func(cmd *cobra.Command, args []string) {
+		from, err := time.Parse(util.LayoutDate, backfillFrom)
+		if err != nil {
+			log.Fatal(err)
+		}
+		to := time.Now().UTC()
+		if backfillTo != "" {
+			to, err = time.Parse(util.LayoutDate, backfillTo)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		a := app.NewApp()
+		if err := a.Backfill(cfg, debug, from, to); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(backfillCmd)
+
+	backfillCmd.Flags().StringVar(&backfillFrom, "from", "", "first date of the range in format YYYY-MM-DD")
+	backfillCmd.Flags().StringVar(&backfillTo, "to", "", "last date of the range in format YYYY-MM-DD (default is today)")
+	backfillCmd.MarkFlagRequired("from") //nolint:errcheck
+}
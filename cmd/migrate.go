@@ -0,0 +1,53 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+
+	"github.com/redselig/currencier/internal/data/app"
+	"github.com/redselig/currencier/internal/data/repository/db"
+)
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "migrate db schema",
+	Long:  `migrate applies or rolls back db schema migrations built in currencier`,
+}
+
+func newMigrateCmd(command, short string) *cobra.Command {
+	return &cobra.Command{
+		Use:   command,
+		Short: short,
+		Run: func(cmd *cobra.Command, args []string) {
+			a := app.NewApp()
+			if err := a.Migrate(cfg, debug, command); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+
+	migrateCmd.AddCommand(newMigrateCmd(db.MigrateUp, "apply all pending migrations"))
+	migrateCmd.AddCommand(newMigrateCmd(db.MigrateDown, "roll back the last migration"))
+	migrateCmd.AddCommand(newMigrateCmd(db.MigrateStatus, "print migrations status"))
+}
@@ -19,11 +19,13 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	"github.com/redselig/currencier/internal/data/app"
+	"github.com/redselig/currencier/internal/domain/usecase"
 )
 
 var cfgFile string
@@ -50,6 +52,8 @@ func init() {
 	// when this action is called directly.
 
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "set if you want run app in debug mode")
+	rootCmd.PersistentFlags().Bool("migrate", false, "apply db schema migrations before start (overrides db.automigrate)")
+	viper.BindPFlag("db.automigrate", rootCmd.PersistentFlags().Lookup("migrate")) //nolint:errcheck
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -60,6 +64,17 @@ func initConfig() {
 	}
 
 	viper.AutomaticEnv() // read in environment variables that match
+	viper.SetDefault("log.level", "info")
+	viper.SetDefault("rates.scale", usecase.DefaultScale)
+	viper.SetDefault("update.retry.attempts", 3)
+	viper.SetDefault("update.retry.delay", 2*time.Second)
+	viper.SetDefault("update.retry.maxdelay", time.Minute)
+	viper.SetDefault("update.breaker.failures", 3)
+	viper.SetDefault("update.breaker.timeout", 10*time.Minute)
+	viper.SetDefault("update.leader.lock", "currencier-updater")
+	viper.SetDefault("update.leader.interval", 15*time.Second)
+	viper.SetDefault("api.health.maxratesage", 96*time.Hour)
+	viper.SetDefault("api.health.updatetimeout", 15*time.Minute)
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err != nil {
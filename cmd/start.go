@@ -25,7 +25,7 @@ import (
 var startCmd = &cobra.Command{
 	Use:   "start",
 	Short: "currencier",
-	Long:  `currencier shows currencies`,
+	Long:  `currencier shows currencies and updates them by schedule`,
 	Run: func(cmd *cobra.Command, args []string) {
 		a := app.NewApp()
 		if err := a.Start(cfg, debug); err != nil {
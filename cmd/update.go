@@ -0,0 +1,50 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+
+	"github.com/redselig/currencier/internal/data/app"
+)
+
+var updateOnce bool
+
+// updateCmd represents the update command
+var updateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "update currencies",
+	Long: `update loads and stores the latest currencies once and exits with non-zero code on failure,
+with --once=false it keeps updating them by schedule like updater`,
+	Run: func(cmd *cobra.Command, args []string) {
+		a := app.NewApp()
+		run := a.UpdateOnce
+		if !updateOnce {
+			run = a.Updater
+		}
+		if err := run(cfg, debug); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(updateCmd)
+
+	updateCmd.Flags().BoolVar(&updateOnce, "once", true, "update currencies once and exit")
+}
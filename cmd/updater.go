@@ -0,0 +1,41 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+
+	"github.com/redselig/currencier/internal/data/app"
+)
+
+// updaterCmd represents the updater command
+var updaterCmd = &cobra.Command{
+	Use:   "updater",
+	Short: "update currencies by schedule",
+	Long:  `updater loads and stores currencies by update.schedule without serving the api`,
+	Run: func(cmd *cobra.Command, args []string) {
+		a := app.NewApp()
+		if err := a.Updater(cfg, debug); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(updaterCmd)
+}
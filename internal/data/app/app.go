@@ -2,20 +2,31 @@ package app
 
 import (
 	"context"
+	"database/sql"
 	"net"
 	"os"
 	"os/signal"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/redselig/currencier/internal/data/controllers"
+	"github.com/redselig/currencier/internal/data/leader"
 	"github.com/redselig/currencier/internal/data/logger/zerologger"
+	"github.com/redselig/currencier/internal/data/metrics"
 	"github.com/redselig/currencier/internal/data/repository/db"
+	"github.com/redselig/currencier/internal/data/repository/memory"
+	"github.com/redselig/currencier/internal/data/scheduler"
+	"github.com/redselig/currencier/internal/data/tracing"
+	"github.com/redselig/currencier/internal/domain/entity"
 	"github.com/redselig/currencier/internal/domain/usecase"
+	"github.com/redselig/currencier/internal/util"
 )
 
+const tracerName = "github.com/redselig/currencier/internal/data/app"
+
 type App struct {
 }
 
@@ -23,75 +34,446 @@ func NewApp() *App {
 	return &App{}
 }
 
-func (a *App) Start(cfg *Config, debug bool) (err error) {
+// Start serves the API and runs the scheduled updates in one process.
+func (a *App) Start(cfg *Config, debug bool) error {
+	return a.run(cfg, debug, true, true)
+}
 
-	wr := os.Stdout
-	if !debug {
-		wr, err = os.OpenFile(cfg.Log.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-		if err != nil {
-			return errors.Wrapf(err, "can't create/open log file")
-		}
+// Serve serves the API only, the rates are updated by another process.
+func (a *App) Serve(cfg *Config, debug bool) error {
+	return a.run(cfg, debug, true, false)
+}
+
+// Updater runs the scheduled updates only.
+func (a *App) Updater(cfg *Config, debug bool) error {
+	return a.run(cfg, debug, false, true)
+}
+
+// UpdateOnce loads and stores the latest rates once.
+func (a *App) UpdateOnce(cfg *Config, debug bool) error {
+	logger, repo, currensier, err := newCurrencier(cfg, debug, metrics.NewMetrics())
+	if err != nil {
+		return err
 	}
-	logger := zerologger.NewLogger(wr, debug)
-	client := controllers.NewHTTPClient(cfg.Update.Source, 30)
-	repo, err := db.NewPGSRepo(cfg.DB.Dialect, cfg.DB.DSN)
+	defer repo.Close()
+	shutdown, err := setupTracing(cfg)
 	if err != nil {
-		return errors.Wrap(err, "cant't initialize repository")
+		return err
 	}
-	currensier := usecase.NewCurrencierInteractor(client, repo)
-	server := controllers.NewHttpServer(net.JoinHostPort("0.0.0.0", cfg.API.HTTPPort), logger, currensier)
-	ctx, cancel := context.WithCancel(context.Background())
+	defer shutdown()
+
+	ctx, cancel := signalContext()
+	defer cancel()
+	if err := traced(ctx, "currencies update", currensier.UpdateCurrencies); err != nil {
+		return errors.Wrap(err, "can't update currencies in repo")
+	}
+	logger.Info(ctx, "currencies updated")
+	return nil
+}
+
+// run serves the API and runs the updater as asked, the metrics are served anyway.
+func (a *App) run(cfg *Config, debug bool, serve, update bool) error {
+	m := metrics.NewMetrics()
+	logger, repo, currensier, err := newCurrencier(cfg, debug, m)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+	shutdown, err := setupTracing(cfg)
+	if err != nil {
+		return err
+	}
+	defer shutdown()
+	if p, ok := repo.(pooled); ok {
+		m.RegisterDB(cfg.DB.Dialect, p.DB())
+	}
+	m.RegisterRatesAge(repo)
+
+	var updater *scheduler.Scheduler
+	if update {
+		if updater, err = newUpdater(cfg, currensier, repo, logger); err != nil {
+			return errors.Wrap(err, "can't start updating currencies")
+		}
+	}
+	ctx, cancel := signalContext()
+	defer cancel()
 	wg := &sync.WaitGroup{}
+	var api usecase.Currencier
+	if serve {
+		api = currensier
+	}
+	server := controllers.NewHttpServer(net.JoinHostPort("0.0.0.0", cfg.API.HTTPPort), logger, api, m,
+		newHealth(cfg, repo, updater, serve))
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		if err := server.Serve(); err != nil {
-			logger.Log(ctx, errors.Wrapf(err, "can't start http server"))
+			logger.Error(ctx, "can't start http server", usecase.Err(err))
+			cancel()
 		}
 	}()
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		if err := a.update(ctx, currensier, cfg.Update.Time, logger); err != nil {
-			logger.Log(context.Background(), errors.Wrapf(err, "can't start updating currencies"))
-		}
+		<-ctx.Done()
+		server.StopServe()
 	}()
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-	<-c
-	cancel()
-	server.StopServe()
+	if update {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runUpdater(ctx, cfg, repo, updater, logger)
+		}()
+	}
 	wg.Wait()
 	return nil
 }
 
-func (a *App) update(ctx context.Context, currencier usecase.Currencier, timeout string, logger usecase.Logger) error {
-	defer logger.Log(ctx, "stop update currencies in repo")
-
-	d, err := time.ParseDuration(timeout)
+// newCurrencier creates the interactor over the configured providers and repository instrumented with m,
+// the db schema is migrated first if db.automigrate is set.
+func newCurrencier(cfg *Config, debug bool, m *metrics.Metrics) (usecase.Logger, repository, *usecase.CurrencierInteractor, error) {
+	logger, err := newLogger(cfg, debug)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	providers, err := newProviders(cfg, logger, m)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	repo, err := newRepo(cfg)
 	if err != nil {
-		return errors.Wrap(err, "cant't parse update timeout")
+		return nil, nil, nil, errors.Wrap(err, "cant't initialize repository")
 	}
-	ticker := time.NewTicker(d)
+	if cfg.DB.AutoMigrate {
+		if err := migrate(repo, db.MigrateUp); err != nil {
+			repo.Close()
+			return nil, nil, nil, err
+		}
+	}
+	return logger, repo, usecase.NewCurrencierInteractor(providers, m.Repository(repo), cfg.Rates.Scale, logger), nil
+}
 
-	if err := currencier.UpdateCurrencies(ctx); err != nil {
-		logger.Log(ctx, errors.Wrapf(err, "can't update currencies in repo"))
+// setupTracing installs the tracer provider, the returned shutdown exports the spans left.
+func setupTracing(cfg *Config) (func(), error) {
+	shutdown, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		return nil, err
 	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown(ctx) //nolint:errcheck
+	}, nil
+}
 
-	for {
+// traced runs the job in a new trace, it's the root span of the background work,
+// the job gets its own request id unless ctx has one.
+func traced(ctx context.Context, name string, job func(ctx context.Context) error) (err error) {
+	ctx, span := tracing.Start(util.SetRequestID(ctx), tracerName, name)
+	defer func() { tracing.End(span, err) }()
+	return job(ctx)
+}
+
+// signalContext returns context cancelled on interrupt or termination.
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		defer signal.Stop(c)
 		select {
+		case <-c:
+			cancel()
 		case <-ctx.Done():
-			ticker.Stop()
-			return nil
-		default:
 		}
-		select {
-		case <-ctx.Done():
+	}()
+	return ctx, cancel
+}
+
+// pooled is implemented by sql repositories.
+type pooled interface {
+	DB() *sql.DB
+}
+
+// pinger is implemented by repositories which keep rates in a db.
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// newHealth creates the probes checks: the db connection and the rates freshness for readiness of the API,
+// the updater is alive while its update runs no longer than api.health.updatetimeout.
+func newHealth(cfg *Config, repo repository, updater *scheduler.Scheduler, serve bool) controllers.Health {
+	health := controllers.Health{
+		Live:  map[string]controllers.Check{},
+		Ready: map[string]controllers.Check{},
+	}
+	if p, ok := repo.(pinger); ok {
+		health.Ready["db"] = p.Ping
+	}
+	if maxAge := cfg.API.Health.MaxRatesAge; serve && maxAge > 0 {
+		health.Ready["rates"] = func(ctx context.Context) error {
+			newest, err := repo.GetNewestDate(ctx)
+			if err != nil {
+				return err
+			}
+			if age := time.Since(newest); age > maxAge {
+				return errors.Errorf("the newest rates on %v are older than %v", newest.Format(util.LayoutDate), maxAge)
+			}
 			return nil
-		case <-ticker.C:
-			if err := currencier.UpdateCurrencies(ctx); err != nil {
-				logger.Log(ctx, errors.Wrapf(err, "can't update currencies in repo"))
+		}
+	}
+	if timeout := cfg.API.Health.UpdateTimeout; updater != nil && timeout > 0 {
+		health.Live["updater"] = func(ctx context.Context) error {
+			if since := updater.RunningSince(); !since.IsZero() && time.Since(since) > timeout {
+				return errors.Errorf("update is running since %v", since.Format(util.LayoutISO))
 			}
+			return nil
+		}
+	}
+	return health
+}
+
+// locker is implemented by repositories shared by replicas, which have to elect the one running the updater.
+type locker interface {
+	TryLock(ctx context.Context, name string) (*db.AdvisoryLock, error)
+}
+
+// runUpdater runs the updater until ctx is done, on the elected replica only if the repository is shared.
+func runUpdater(ctx context.Context, cfg *Config, repo repository, updater *scheduler.Scheduler, logger usecase.Logger) {
+	l, ok := repo.(locker)
+	if !ok {
+		updater.Run(ctx)
+		return
+	}
+	tryLock := func(ctx context.Context) (leader.Lock, error) {
+		lock, err := l.TryLock(ctx, cfg.Update.Leader.Lock)
+		if lock == nil {
+			return nil, err
+		}
+		return lock, nil
+	}
+	leader.NewElector("currencies updater", tryLock, cfg.Update.Leader.Interval, logger).Run(ctx, updater.Run)
+}
+
+// newUpdater creates scheduler of the latest rates updates by update.schedule, every update.time if there is no cron.
+func newUpdater(cfg *Config, currencier usecase.Currencier, repo entity.CurrencyInternalRepository, logger usecase.Logger) (*scheduler.Scheduler, error) {
+	sched := cfg.Update.Schedule
+	spec := sched.Cron
+	if spec == "" {
+		if cfg.Update.Time == "" {
+			return nil, errors.New("update.schedule.cron or update.time must be set")
+		}
+		spec = "@every " + cfg.Update.Time
+	}
+	location := time.UTC
+	if sched.TimeZone != "" {
+		var err error
+		if location, err = time.LoadLocation(sched.TimeZone); err != nil {
+			return nil, errors.Wrapf(err, "can't load time zone %v", sched.TimeZone)
+		}
+	}
+	var ahead int
+	switch sched.Until {
+	case "", UntilToday:
+	case UntilNextDay:
+		ahead = 1
+	default:
+		return nil, errors.Errorf("unknown update.schedule.until %v", sched.Until)
+	}
+
+	job := func(ctx context.Context) error {
+		if err := traced(ctx, "currencies update", currencier.UpdateCurrencies); err != nil {
+			return errors.Wrap(err, "can't update currencies in repo")
+		}
+		if sched.Until == "" {
+			return nil
+		}
+		now := time.Now().In(location)
+		want := time.Date(now.Year(), now.Month(), now.Day()+ahead, 0, 0, 0, 0, time.UTC)
+		newest, err := repo.GetNewestDate(ctx)
+		if err != nil {
+			return errors.Wrap(err, "can't check updated currencies")
+		}
+		if newest.Before(want) {
+			return errors.Errorf("rates on %v are not published yet", want.Format(util.LayoutDate))
+		}
+		return nil
+	}
+	return scheduler.NewScheduler("currencies update", scheduler.Config{
+		Spec:       spec,
+		Location:   location,
+		RetryEvery: sched.RetryEvery,
+		RunOnStart: true,
+	}, job, logger)
+}
+
+// Backfill loads rates for every day from the range and stores them to history,
+// it's safe to run it again over the same range.
+func (a *App) Backfill(cfg *Config, debug bool, from, to time.Time) (err error) {
+	if to.Before(from) {
+		return errors.Errorf("wrong date range: %v is before %v", to.Format(util.LayoutDate), from.Format(util.LayoutDate))
+	}
+	logger, repo, currensier, err := newCurrencier(cfg, debug, metrics.NewMetrics())
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+	shutdown, err := setupTracing(cfg)
+	if err != nil {
+		return err
+	}
+	defer shutdown()
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	failed := 0
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		if ctx.Err() != nil {
+			return errors.Wrap(ctx.Err(), "backfill interrupted")
+		}
+		err := traced(ctx, "currencies backfill", func(ctx context.Context) error {
+			return currensier.UpdateCurrenciesOnDate(ctx, date)
+		})
+		if err != nil {
+			failed++
+			logger.Error(ctx, "can't backfill currencies", usecase.String("date", date.Format(util.LayoutDate)), usecase.Err(err))
+			continue
+		}
+		logger.Info(ctx, "currencies backfilled", usecase.String("date", date.Format(util.LayoutDate)))
+	}
+	if failed > 0 {
+		return errors.Errorf("can't backfill currencies for %v days, see log for details", failed)
+	}
+	return nil
+}
+
+// Migrate runs up, down or status command of the schema migrations for the configured db.
+func (a *App) Migrate(cfg *Config, debug bool, command string) error {
+	logger, err := newLogger(cfg, debug)
+	if err != nil {
+		return err
+	}
+	repo, err := newRepo(cfg)
+	if err != nil {
+		return errors.Wrap(err, "cant't initialize repository")
+	}
+	defer repo.Close()
+	if err := migrate(repo, command); err != nil {
+		return err
+	}
+	logger.Info(context.Background(), "migrate done", usecase.String("command", command), usecase.String("dialect", cfg.DB.Dialect))
+	return nil
+}
+
+// newProviders creates rates providers in the configured order, the CBR feed from update.source if there are none.
+// Each provider retries transient failures and is skipped by its circuit breaker while it's down,
+// every fetch including retries is counted by m.
+func newProviders(cfg *Config, logger usecase.Logger, m *metrics.Metrics) ([]entity.CurrencyExternalRepository, error) {
+	pcfgs := cfg.Update.Providers
+	if len(pcfgs) == 0 {
+		pcfgs = []Provider{{Type: controllers.CBRProvider, Source: cfg.Update.Source}}
+	}
+	providers := make([]entity.CurrencyExternalRepository, 0, len(pcfgs))
+	for _, pcfg := range pcfgs {
+		p, err := controllers.NewProvider(controllers.ProviderConfig{
+			Name:       pcfg.Name,
+			Type:       pcfg.Type,
+			Source:     pcfg.Source,
+			DateSource: pcfg.DateSource,
+			Timeout:    30,
+		})
+		if err != nil {
+			return nil, err
+		}
+		name := pcfg.Name
+		if name == "" {
+			name = pcfg.Type
+		}
+		resilient := controllers.NewResilientProvider(name, m.Provider(name, p),
+			controllers.RetryConfig{
+				Attempts: cfg.Update.Retry.Attempts,
+				Delay:    cfg.Update.Retry.Delay,
+				MaxDelay: cfg.Update.Retry.MaxDelay,
+			},
+			controllers.BreakerConfig{
+				Failures: cfg.Update.Breaker.Failures,
+				Timeout:  cfg.Update.Breaker.Timeout,
+			}, logger)
+		if err := m.RegisterBreaker(name, resilient.State, controllers.BreakerStates...); err != nil {
+			return nil, errors.Wrapf(err, "can't expose circuit breaker of provider %v, provider names must be unique", name)
+		}
+		providers = append(providers, resilient)
+	}
+	return providers, nil
+}
+
+// migrator is implemented by repositories having a schema.
+type migrator interface {
+	Migrate(command string) error
+}
+
+func migrate(repo repository, command string) error {
+	m, ok := repo.(migrator)
+	if !ok {
+		return nil
+	}
+	return m.Migrate(command)
+}
+
+type repository interface {
+	entity.CurrencyInternalRepository
+	Close() error
+}
+
+// newRepo creates repository for the db dialect, the dialect is used as sql driver name for sql databases.
+func newRepo(cfg *Config) (repository, error) {
+	switch cfg.DB.Dialect {
+	case memory.Dialect:
+		return memory.NewMemRepo(), nil
+	case db.MySQLDialect:
+		repo, err := db.NewMySQLRepo(cfg.DB.DSN)
+		if err != nil {
+			return nil, err
+		}
+		return repo, nil
+	case db.SQLiteDialect:
+		repo, err := db.NewSQLiteRepo(cfg.DB.DSN)
+		if err != nil {
+			return nil, err
+		}
+		return repo, nil
+	default:
+		repo, err := db.NewPGSRepo(cfg.DB.Dialect, cfg.DB.DSN)
+		if err != nil {
+			return nil, err
+		}
+		return repo, nil
+	}
+}
+
+// newLogger creates logger writing to log.file at log.level, in debug mode it writes everything to stdout.
+func newLogger(cfg *Config, debug bool) (*zerologger.Logger, error) {
+	wr := os.Stdout
+	if !debug {
+		var err error
+		wr, err = os.OpenFile(cfg.Log.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, errors.Wrapf(err, "can't create/open log file")
 		}
 	}
+	level := cfg.Log.Level
+	if debug {
+		level = zerologger.LevelDebug
+	}
+	logger, err := zerologger.NewLogger(wr, level)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't create logger")
+	}
+	return logger, nil
 }
@@ -1,26 +1,106 @@
 package app
 
+import "time"
+
 type Config struct {
-	Log    Log    `yaml:"log"`
-	DB     DB     `yaml:"db"`
-	Update Update `yaml:"update"`
-	API    API    `yaml:"api"`
+	Log     Log     `yaml:"log"`
+	DB      DB      `yaml:"db"`
+	Update  Update  `yaml:"update"`
+	API     API     `yaml:"api"`
+	Rates   Rates   `yaml:"rates"`
+	Tracing Tracing `yaml:"tracing"`
 }
 
+// Log is written to File, the records below Level (debug, info, warn or error) are dropped.
 type Log struct {
-	File string `yaml:"file"`
+	File  string `yaml:"file"`
+	Level string `yaml:"level"`
 }
 
 type API struct {
 	HTTPPort string `yaml:"httpport"`
+	Health   Health `yaml:"health"`
+}
+
+// Health sets when the probes fail: the service isn't ready if the db is down or the newest rate is older
+// than MaxRatesAge (0 disables the check), it isn't alive if an update runs longer than UpdateTimeout.
+type Health struct {
+	MaxRatesAge   time.Duration `yaml:"maxratesage"`
+	UpdateTimeout time.Duration `yaml:"updatetimeout"`
 }
 
 type DB struct {
-	DSN     string `yaml:"dsn"`
-	Dialect string `yaml:"dialect"`
+	DSN         string `yaml:"dsn"`
+	Dialect     string `yaml:"dialect"`
+	AutoMigrate bool   `yaml:"automigrate"`
 }
 
 type Update struct {
-	Time   string `yaml:"time"`
-	Source string `yaml:"source"`
+	// Time is the interval of updates used when there is no Schedule.Cron.
+	Time     string   `yaml:"time"`
+	Schedule Schedule `yaml:"schedule"`
+	Leader   Leader   `yaml:"leader"`
+	// Source is the CBR feed url used when no Providers are configured.
+	Source    string     `yaml:"source"`
+	Providers []Provider `yaml:"providers"`
+	// Retry and Breaker apply to every provider.
+	Retry   Retry   `yaml:"retry"`
+	Breaker Breaker `yaml:"breaker"`
+}
+
+const (
+	// UntilToday retries updates until the rates set on the day of the run appear.
+	UntilToday = "today"
+	// UntilNextDay retries updates until the rates set on the day after the run appear, the CBR publishes them a day ahead.
+	UntilNextDay = "nextday"
+)
+
+// Schedule runs updates by Cron in TimeZone and repeats a failed one every RetryEvery until the next run,
+// with Until set the update also fails until the rates of the expected day are stored.
+type Schedule struct {
+	Cron       string        `yaml:"cron"`
+	TimeZone   string        `yaml:"timezone"`
+	RetryEvery time.Duration `yaml:"retryevery"`
+	Until      string        `yaml:"until"`
+}
+
+// Leader is the db lock replicas sharing the db elect the one running updates by,
+// the others try to take it every Interval.
+type Leader struct {
+	Lock     string        `yaml:"lock"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+// Retry repeats loads failed with transient errors after Delay doubled for every next attempt up to MaxDelay.
+type Retry struct {
+	Attempts int           `yaml:"attempts"`
+	Delay    time.Duration `yaml:"delay"`
+	MaxDelay time.Duration `yaml:"maxdelay"`
+}
+
+// Breaker stops calling a provider for Timeout after Failures failed loads in a row.
+type Breaker struct {
+	Failures int           `yaml:"failures"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+// Provider is a rates source, the providers are tried in the configured order.
+type Provider struct {
+	Name       string `yaml:"name"`
+	Type       string `yaml:"type"`
+	Source     string `yaml:"source"`
+	DateSource string `yaml:"datesource"`
+}
+
+type Rates struct {
+	Scale int32 `yaml:"scale"`
+}
+
+// Tracing exports spans to the OTLP/HTTP collector at Endpoint (localhost:4318 by default) if Exporter is otlp,
+// to stdout if it's stdout, tracing is disabled if it's empty.
+type Tracing struct {
+	Exporter    string  `yaml:"exporter"`
+	Endpoint    string  `yaml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"`
+	SampleRatio float64 `yaml:"sampleratio"`
 }
@@ -5,13 +5,14 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
-	"math"
 	"net/http"
-	"strconv"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
 	"golang.org/x/text/encoding/charmap"
 
 	"github.com/redselig/currencier/internal/domain/entity"
@@ -20,46 +21,117 @@ import (
 const (
 	ErrLoad = "can't pull currency prices from site: %v"
 	ErrXML  = "can't extract xml data"
+
+	layoutCBRDate    = "02.01.2006"
+	layoutCBRDateReq = "02/01/2006"
 )
 
 var _ entity.CurrencyExternalRepository = (*HTTPClient)(nil)
 
+// HTTPClient loads daily rates from the CBR XML feed, the latest rates are requested conditionally
+// and the previous ones are returned again if the feed isn't modified.
 type HTTPClient struct {
+	name   string
 	url    string
 	client *http.Client
+
+	mu           sync.Mutex
+	etag         string
+	lastModified string
+	latest       []*entity.Currency
 }
 
-func NewHTTPClient(url string, timeout time.Duration) *HTTPClient {
+// NewHTTPClient creates CBR client, name is the provider rates are marked with.
+func NewHTTPClient(name, url string, timeout time.Duration) *HTTPClient {
 	client := &http.Client{Timeout: timeout * time.Second}
 	return &HTTPClient{
+		name:   name,
 		url:    url,
 		client: client}
 }
 func (hc *HTTPClient) Load(ctx context.Context) ([]*entity.Currency, error) {
+	return hc.load(ctx, hc.url)
+}
+
+// LoadOnDate loads rates the CBR set on the date, for weekends and holidays it's the last business day rates.
+func (hc *HTTPClient) LoadOnDate(ctx context.Context, date time.Time) ([]*entity.Currency, error) {
+	u, err := url.Parse(hc.url)
+	if err != nil {
+		return nil, errors.Wrapf(err, ErrLoad, hc.url)
+	}
+	q := u.Query()
+	q.Set("date_req", date.Format(layoutCBRDateReq))
+	u.RawQuery = q.Encode()
+	return hc.load(ctx, u.String())
+}
+
+func (hc *HTTPClient) load(ctx context.Context, source string) ([]*entity.Currency, error) {
 	req, err := http.NewRequest(
-		"GET", hc.url, nil,
+		"GET", source, nil,
 	)
 	if err != nil {
-		return nil, errors.Wrapf(err, ErrLoad, hc.url)
+		return nil, errors.Wrapf(err, ErrLoad, source)
 	}
 
 	req.Header.Add("Accept", "text/html")
 	req.Header.Add("User-Agent", "MSIE/15.0")
-	req = req.WithContext(ctx)
+	latest := source == hc.url
+	if latest {
+		hc.mu.Lock()
+		if hc.etag != "" {
+			req.Header.Set("If-None-Match", hc.etag)
+		}
+		if hc.lastModified != "" {
+			req.Header.Set("If-Modified-Since", hc.lastModified)
+		}
+		hc.mu.Unlock()
+	}
 
-	resp, err := hc.client.Do(req)
+	resp, err := do(ctx, hc.client, hc.name, req)
 	if err != nil {
-		return nil, errors.Wrapf(err, ErrLoad, hc.url)
+		return nil, errors.Wrapf(err, ErrLoad, source)
 	}
 	defer resp.Body.Close()
+	if latest && resp.StatusCode == http.StatusNotModified {
+		hc.mu.Lock()
+		defer hc.mu.Unlock()
+		if hc.latest != nil {
+			return cloneCurrencies(hc.latest), nil
+		}
+	}
+	if err := checkStatus(resp); err != nil {
+		return nil, errors.Wrapf(err, ErrLoad, source)
+	}
 
-	cs, err := XMLExtract(resp.Body)
+	cs, err := parse(ctx, "xml", func() ([]*entity.Currency, error) {
+		return XMLExtract(resp.Body)
+	})
 	if err != nil {
-		return nil, errors.Wrapf(err, ErrLoad, hc.url)
+		return nil, errors.Wrapf(err, ErrLoad, source)
+	}
+	for _, c := range cs {
+		c.Provider = hc.name
+	}
+	if latest {
+		hc.mu.Lock()
+		hc.etag = resp.Header.Get("ETag")
+		hc.lastModified = resp.Header.Get("Last-Modified")
+		hc.latest = cloneCurrencies(cs)
+		hc.mu.Unlock()
 	}
 	return cs, nil
 }
 
+// cloneCurrencies copies the rates so the cached ones can't be changed by the caller.
+func cloneCurrencies(cs []*entity.Currency) []*entity.Currency {
+	cloned := make([]*entity.Currency, 0, len(cs))
+	for _, c := range cs {
+		cc := *c
+		cloned = append(cloned, &cc)
+	}
+	return cloned
+}
+
 func XMLExtract(rc io.ReadCloser) ([]*entity.Currency, error) {
 	decoded := xml.NewDecoder(rc)
 	decoded.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
@@ -82,17 +154,28 @@ func XMLExtract(rc io.ReadCloser) ([]*entity.Currency, error) {
 	if err != nil {
 		return nil, errors.Wrapf(err, ErrXML)
 	}
+	var date time.Time
+	if vals.Date != "" {
+		date, err = time.Parse(layoutCBRDate, vals.Date)
+		if err != nil {
+			return nil, errors.Wrapf(err, ErrXML)
+		}
+	}
+	for _, c := range cs {
+		c.Date = date
+		c.Market = vals.Name
+		c.Base = CBRBase
+	}
 	return cs, nil
 }
 
 func XMLValutesToCurrencies(vls []Valute) ([]*entity.Currency, error) {
 	cs := []*entity.Currency{}
 	for _, valute := range vls {
-		rate, err := strconv.ParseFloat(strings.Replace(valute.Value, ",", ".", 1), 64)
+		rate, err := decimal.NewFromString(strings.Replace(valute.Value, ",", ".", 1))
 		if err != nil {
 			return nil, err
 		}
-		rate = math.Round(rate*100) / 100
 		c := entity.Currency{
 			ID:       valute.ID,
 			NumCode:  valute.NumCode,
@@ -107,6 +190,8 @@ func XMLValutesToCurrencies(vls []Valute) ([]*entity.Currency, error) {
 }
 
 type ValCurs struct {
+	Date   string   `xml:"Date,attr"`
+	Name   string   `xml:"name,attr"`
 	Valute []Valute `xml:"Valute"`
 }
 type Valute struct {
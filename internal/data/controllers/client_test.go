@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testRatesDate = time.Date(2020, 3, 3, 0, 0, 0, 0, time.UTC)
+
+func TestXMLExtract(t *testing.T) {
+	f, err := os.Open("testdata/XML_daily.xml")
+	require.Nil(t, err)
+	defer f.Close()
+
+	cs, err := XMLExtract(f)
+	require.Nil(t, err)
+	require.Len(t, cs, 3)
+	require.Equal(t, "R01020A", cs[0].ID)
+	require.Equal(t, "Азербайджанский манат", cs[0].Name)
+	require.Equal(t, "USD", cs[1].CharCode)
+	require.Equal(t, 840, cs[1].NumCode)
+	require.Equal(t, 100, cs[2].Nominal)
+	require.Equal(t, "61.5012", cs[2].Value.String())
+	for _, c := range cs {
+		require.Equal(t, testRatesDate, c.Date)
+		require.Equal(t, "Foreign Currency Market", c.Market)
+		require.Equal(t, CBRBase, c.Base)
+	}
+}
+
+func TestHTTPClient_LoadOnDate(t *testing.T) {
+	fixture, err := ioutil.ReadFile("testdata/XML_daily.xml")
+	require.Nil(t, err)
+
+	var dateReq string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dateReq = r.URL.Query().Get("date_req")
+		w.Write(fixture) //nolint:errcheck
+	}))
+	defer ts.Close()
+
+	client := NewHTTPClient(CBRProvider, ts.URL+"/scripts/XML_daily.asp", 5)
+	cs, err := client.LoadOnDate(context.Background(), testRatesDate)
+	require.Nil(t, err)
+	require.Equal(t, "03/03/2020", dateReq)
+	require.Len(t, cs, 3)
+	require.Equal(t, CBRProvider, cs[0].Provider)
+}
+
+func TestHTTPClient_LoadNotModified(t *testing.T) {
+	fixture, err := ioutil.ReadFile("testdata/XML_daily.xml")
+	require.Nil(t, err)
+
+	const etag = `"5e5d9c80-1c2f"`
+	var written int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		written++
+		w.Header().Set("ETag", etag)
+		w.Write(fixture) //nolint:errcheck
+	}))
+	defer ts.Close()
+
+	client := NewHTTPClient(CBRProvider, ts.URL+"/scripts/XML_daily.asp", 5)
+	first, err := client.Load(context.Background())
+	require.Nil(t, err)
+	first[0].Value = first[0].Value.Neg()
+
+	cs, err := client.Load(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, 1, written)
+	require.Len(t, cs, 3)
+	require.Equal(t, "39.1256", cs[0].Value.String())
+	require.Equal(t, CBRProvider, cs[0].Provider)
+}
@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+
+	"github.com/redselig/currencier/internal/domain/entity"
+)
+
+const (
+	ECBProvider = "ecb"
+	// ECBBase is the currency the ECB sets its reference rates to.
+	ECBBase = "EUR"
+
+	ECBDailySource = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+	ECBHistSource  = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-hist.xml"
+
+	ErrECB = "can't extract ecb xml data"
+
+	layoutECBDate = "2006-01-02"
+
+	// ecbScale is the number of decimal places of the converted ECB rates.
+	ecbScale = 8
+	// ecbHistoryTTL is how long the downloaded historical feed is reused, so a backfill downloads it once.
+	ecbHistoryTTL = time.Hour
+)
+
+var _ entity.CurrencyExternalRepository = (*ECBClient)(nil)
+
+// ECBClient loads euro foreign exchange reference rates of the European Central Bank.
+type ECBClient struct {
+	name    string
+	url     string
+	histURL string
+	client  *http.Client
+
+	// the historical feed downloaded at historyAt
+	mu        sync.Mutex
+	history   *ECBEnvelope
+	historyAt time.Time
+}
+
+// NewECBClient creates ECB client, histURL is the historical rates feed used to load rates on a date.
+func NewECBClient(name, url, histURL string, timeout time.Duration) *ECBClient {
+	if url == "" {
+		url = ECBDailySource
+	}
+	if histURL == "" {
+		histURL = ECBHistSource
+	}
+	client := &http.Client{Timeout: timeout * time.Second}
+	return &ECBClient{
+		name:    name,
+		url:     url,
+		histURL: histURL,
+		client:  client}
+}
+
+func (ec *ECBClient) Load(ctx context.Context) ([]*entity.Currency, error) {
+	envelope, err := ec.fetch(ctx, ec.url)
+	if err != nil {
+		return nil, err
+	}
+	return ec.rates(ctx, ec.url, envelope, time.Time{})
+}
+
+// LoadOnDate loads the rates set on the date, for weekends and holidays it's the previous business day rates.
+func (ec *ECBClient) LoadOnDate(ctx context.Context, date time.Time) ([]*entity.Currency, error) {
+	envelope, err := ec.historyFeed(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ec.rates(ctx, ec.histURL, envelope, date)
+}
+
+// historyFeed returns the historical feed downloaded within ecbHistoryTTL, it's downloaded again after that.
+func (ec *ECBClient) historyFeed(ctx context.Context) (*ECBEnvelope, error) {
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+
+	if ec.history != nil && time.Since(ec.historyAt) < ecbHistoryTTL {
+		return ec.history, nil
+	}
+	envelope, err := ec.fetch(ctx, ec.histURL)
+	if err != nil {
+		return nil, err
+	}
+	ec.history, ec.historyAt = envelope, time.Now()
+	return envelope, nil
+}
+
+func (ec *ECBClient) fetch(ctx context.Context, source string) (*ECBEnvelope, error) {
+	req, err := http.NewRequest(
+		"GET", source, nil,
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, ErrLoad, source)
+	}
+	req.Header.Add("Accept", "application/xml")
+
+	resp, err := do(ctx, ec.client, ec.name, req)
+	if err != nil {
+		return nil, errors.Wrapf(err, ErrLoad, source)
+	}
+	defer resp.Body.Close()
+	if err := checkStatus(resp); err != nil {
+		return nil, errors.Wrapf(err, ErrLoad, source)
+	}
+
+	envelope, err := ECBDecode(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, ErrLoad, source)
+	}
+	return envelope, nil
+}
+
+func (ec *ECBClient) rates(ctx context.Context, source string, envelope *ECBEnvelope, date time.Time) ([]*entity.Currency, error) {
+	cs, err := parse(ctx, "xml", func() ([]*entity.Currency, error) {
+		return ECBRates(envelope, date)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, ErrLoad, source)
+	}
+	for _, c := range cs {
+		c.Provider = ec.name
+	}
+	return cs, nil
+}
+
+// ECBExtract parses daily or historical ECB feed and returns the rates of the latest day not after date,
+// the latest day in the feed if date is zero. ECB sets how many units of a currency one euro costs,
+// the rates are turned to the CBR shape: Value is the price of Nominal units in euro,
+// Nominal is a power of ten keeping Value not less than one like the CBR does for cheap currencies.
+func ECBExtract(r io.Reader, date time.Time) ([]*entity.Currency, error) {
+	envelope, err := ECBDecode(r)
+	if err != nil {
+		return nil, err
+	}
+	return ECBRates(envelope, date)
+}
+
+// ECBDecode parses daily or historical ECB feed.
+func ECBDecode(r io.Reader) (*ECBEnvelope, error) {
+	envelope := &ECBEnvelope{}
+	if err := xml.NewDecoder(r).Decode(envelope); err != nil {
+		return nil, errors.Wrap(err, ErrECB)
+	}
+	return envelope, nil
+}
+
+// ECBRates returns the rates of the parsed feed set on the latest day not after date, see ECBExtract.
+func ECBRates(envelope *ECBEnvelope, date time.Time) ([]*entity.Currency, error) {
+	var (
+		found   *ECBDay
+		foundAt time.Time
+	)
+	for i, d := range envelope.Cube.Days {
+		t, err := time.Parse(layoutECBDate, d.Time)
+		if err != nil {
+			return nil, errors.Wrap(err, ErrECB)
+		}
+		if !date.IsZero() && t.After(date) {
+			continue
+		}
+		if found == nil || t.After(foundAt) {
+			found, foundAt = &envelope.Cube.Days[i], t
+		}
+	}
+	if found == nil || len(found.Rates) == 0 {
+		return nil, errors.New(ErrECB + ": no rates")
+	}
+
+	cs := make([]*entity.Currency, 0, len(found.Rates))
+	for _, rate := range found.Rates {
+		perEuro, err := decimal.NewFromString(rate.Rate)
+		if err != nil {
+			return nil, errors.Wrap(err, ErrECB)
+		}
+		if !perEuro.IsPositive() {
+			return nil, errors.Errorf("%v: wrong rate %v of %v", ErrECB, rate.Rate, rate.Currency)
+		}
+		nominal := decimal.New(1, 0)
+		for nominal.LessThan(perEuro) {
+			nominal = nominal.Shift(1)
+		}
+		cs = append(cs, &entity.Currency{
+			ID:       rate.Currency,
+			CharCode: rate.Currency,
+			Nominal:  int(nominal.IntPart()),
+			Name:     rate.Currency,
+			Value:    nominal.DivRound(perEuro, ecbScale),
+			Base:     ECBBase,
+			Date:     foundAt,
+			Market:   envelope.Sender.Name,
+		})
+	}
+	return cs, nil
+}
+
+type ECBEnvelope struct {
+	Sender struct {
+		Name string `xml:"name"`
+	} `xml:"Sender"`
+	Cube struct {
+		Days []ECBDay `xml:"Cube"`
+	} `xml:"Cube"`
+}
+
+type ECBDay struct {
+	Time  string    `xml:"time,attr"`
+	Rates []ECBRate `xml:"Cube"`
+}
+
+type ECBRate struct {
+	Currency string `xml:"currency,attr"`
+	Rate     string `xml:"rate,attr"`
+}
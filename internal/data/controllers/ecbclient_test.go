@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestECBExtract(t *testing.T) {
+	t.Run("daily", func(t *testing.T) {
+		f, err := os.Open("testdata/eurofxref-daily.xml")
+		require.Nil(t, err)
+		defer f.Close()
+
+		cs, err := ECBExtract(f, time.Time{})
+		require.Nil(t, err)
+		require.Len(t, cs, 4)
+		tCases := []struct {
+			code    string
+			nominal int
+			value   string
+		}{
+			{"USD", 10, "8.95094880"},
+			{"JPY", 1000, "8.31600832"},
+			{"GBP", 1, "1.14707839"},
+			{"IDR", 100000, "6.28050757"},
+		}
+		for i, tcase := range tCases {
+			require.Equal(t, tcase.code, cs[i].ID)
+			require.Equal(t, tcase.code, cs[i].CharCode)
+			require.Equal(t, tcase.nominal, cs[i].Nominal)
+			require.Equal(t, tcase.value, cs[i].Value.StringFixed(ecbScale))
+			require.Equal(t, testRatesDate, cs[i].Date)
+			require.Equal(t, "European Central Bank", cs[i].Market)
+			require.Equal(t, ECBBase, cs[i].Base)
+		}
+	})
+	t.Run("historical on weekend", func(t *testing.T) {
+		f, err := os.Open("testdata/eurofxref-hist.xml")
+		require.Nil(t, err)
+		defer f.Close()
+
+		cs, err := ECBExtract(f, time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC))
+		require.Nil(t, err)
+		require.Len(t, cs, 2)
+		require.Equal(t, time.Date(2020, 2, 28, 0, 0, 0, 0, time.UTC), cs[0].Date)
+		require.Equal(t, "9.10995718", cs[0].Value.StringFixed(ecbScale))
+	})
+	t.Run("no rates before date", func(t *testing.T) {
+		f, err := os.Open("testdata/eurofxref-hist.xml")
+		require.Nil(t, err)
+		defer f.Close()
+
+		_, err = ECBExtract(f, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+		require.NotNil(t, err)
+	})
+}
+
+func TestECBClient_LoadOnDate(t *testing.T) {
+	fixture, err := ioutil.ReadFile("testdata/eurofxref-hist.xml")
+	require.Nil(t, err)
+	downloads := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		w.Write(fixture) //nolint:errcheck
+	}))
+	defer ts.Close()
+
+	client, err := NewProvider(ProviderConfig{
+		Type:       ECBProvider,
+		DateSource: ts.URL + "/eurofxref-hist.xml",
+		Timeout:    5,
+	})
+	require.Nil(t, err)
+	cs, err := client.LoadOnDate(context.Background(), testRatesDate)
+	require.Nil(t, err)
+	require.Len(t, cs, 2)
+	require.Equal(t, testRatesDate, cs[0].Date)
+	require.Equal(t, ECBProvider, cs[0].Provider)
+	cs, err = client.LoadOnDate(context.Background(), testRatesDate.AddDate(0, 0, -1))
+	require.Nil(t, err)
+	require.NotEmpty(t, cs)
+	require.Equal(t, 1, downloads)
+}
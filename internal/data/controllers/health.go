@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/redselig/currencier/internal/domain/usecase"
+)
+
+const (
+	HealthOK   = "ok"
+	HealthFail = "fail"
+
+	// healthTimeout limits all the checks of a probe.
+	healthTimeout = 5 * time.Second
+)
+
+// Check returns error if the checked part of the service is broken.
+type Check func(ctx context.Context) error
+
+// Health is the named checks of the liveness (/healthz) and readiness (/readyz) probes,
+// a probe without checks always passes.
+type Health struct {
+	Live  map[string]Check
+	Ready map[string]Check
+}
+
+// HealthResponse is the body of the probes, the causes of failed checks are only logged
+// as they may contain addresses and credentials.
+type HealthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+func (s *HTTPServer) healthz(w http.ResponseWriter, r *http.Request) {
+	s.probe(w, r, "liveness", s.health.Live)
+}
+
+func (s *HTTPServer) readyz(w http.ResponseWriter, r *http.Request) {
+	s.probe(w, r, "readiness", s.health.Ready)
+}
+
+// probe runs the checks in the order of their names and answers 503 if any of them fails.
+func (s *HTTPServer) probe(w http.ResponseWriter, r *http.Request, name string, checks map[string]Check) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthTimeout)
+	defer cancel()
+
+	names := make([]string, 0, len(checks))
+	for n := range checks {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	resp := HealthResponse{Status: HealthOK, Checks: make(map[string]string, len(checks))}
+	for _, n := range names {
+		if err := checks[n](ctx); err != nil {
+			s.logger.Warn(ctx, "probe check failed", usecase.String("probe", name), usecase.String("check", n), usecase.Err(err))
+			resp.Status = HealthFail
+			resp.Checks[n] = HealthFail
+			continue
+		}
+		resp.Checks[n] = HealthOK
+	}
+	code := http.StatusOK
+	if resp.Status != HealthOK {
+		code = http.StatusServiceUnavailable
+	}
+	s.httpAnswer(w, resp, code)
+}
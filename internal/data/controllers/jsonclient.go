@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+
+	"github.com/redselig/currencier/internal/domain/entity"
+)
+
+const (
+	ErrJSON     = "can't extract json data"
+	ErrNoSource = "no source of rates on date"
+)
+
+var _ entity.CurrencyExternalRepository = (*JSONClient)(nil)
+
+// JSONClient loads rates in the JSON format of CBR mirrors like www.cbr-xml-daily.ru:
+//
+//	{"Date": "2020-03-03T11:30:00+03:00", "Valute": {"USD": {"ID": "R01235", "NumCode": "840",
+//	"CharCode": "USD", "Nominal": 1, "Name": "Доллар США", "Value": 66.3274}}}
+type JSONClient struct {
+	name    string
+	url     string
+	dateURL string
+	client  *http.Client
+}
+
+// NewJSONClient creates JSON client, dateURL is the url of rates on a date with {yyyy}, {mm} and {dd} placeholders.
+func NewJSONClient(name, url, dateURL string, timeout time.Duration) *JSONClient {
+	client := &http.Client{Timeout: timeout * time.Second}
+	return &JSONClient{
+		name:    name,
+		url:     url,
+		dateURL: dateURL,
+		client:  client}
+}
+
+func (jc *JSONClient) Load(ctx context.Context) ([]*entity.Currency, error) {
+	return jc.load(ctx, jc.url)
+}
+
+func (jc *JSONClient) LoadOnDate(ctx context.Context, date time.Time) ([]*entity.Currency, error) {
+	if jc.dateURL == "" {
+		return nil, errors.Errorf(ErrLoad+": "+ErrNoSource, jc.url)
+	}
+	source := strings.NewReplacer(
+		"{yyyy}", strconv.Itoa(date.Year()),
+		"{mm}", date.Format("01"),
+		"{dd}", date.Format("02"),
+	).Replace(jc.dateURL)
+	return jc.load(ctx, source)
+}
+
+func (jc *JSONClient) load(ctx context.Context, source string) ([]*entity.Currency, error) {
+	req, err := http.NewRequest(
+		"GET", source, nil,
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, ErrLoad, source)
+	}
+	req.Header.Add("Accept", "application/json")
+
+	resp, err := do(ctx, jc.client, jc.name, req)
+	if err != nil {
+		return nil, errors.Wrapf(err, ErrLoad, source)
+	}
+	defer resp.Body.Close()
+	if err := checkStatus(resp); err != nil {
+		return nil, errors.Wrapf(err, ErrLoad, source)
+	}
+
+	cs, err := parse(ctx, "json", func() ([]*entity.Currency, error) {
+		return JSONExtract(resp.Body)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, ErrLoad, source)
+	}
+	for _, c := range cs {
+		c.Provider = jc.name
+	}
+	return cs, nil
+}
+
+// JSONExtract parses rates sorted by id.
+func JSONExtract(r io.Reader) ([]*entity.Currency, error) {
+	vals := JSONRates{}
+	if err := json.NewDecoder(r).Decode(&vals); err != nil {
+		return nil, errors.Wrap(err, ErrJSON)
+	}
+	if len(vals.Valute) == 0 {
+		return nil, errors.New(ErrJSON + ": no rates")
+	}
+	var date time.Time
+	if vals.Date != "" {
+		t, err := time.Parse(time.RFC3339, vals.Date)
+		if err != nil {
+			return nil, errors.Wrap(err, ErrJSON)
+		}
+		date = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	cs := make([]*entity.Currency, 0, len(vals.Valute))
+	for _, v := range vals.Valute {
+		rate, err := decimal.NewFromString(v.Value.String())
+		if err != nil {
+			return nil, errors.Wrap(err, ErrJSON)
+		}
+		if !rate.IsPositive() {
+			return nil, errors.Errorf("%v: wrong rate %v of %v", ErrJSON, v.Value, v.ID)
+		}
+		numCode, err := v.NumCode.Int64()
+		if err != nil && v.NumCode != "" {
+			return nil, errors.Wrap(err, ErrJSON)
+		}
+		cs = append(cs, &entity.Currency{
+			ID:       v.ID,
+			NumCode:  int(numCode),
+			CharCode: v.CharCode,
+			Nominal:  v.Nominal,
+			Name:     v.Name,
+			Value:    rate,
+			Base:     CBRBase,
+			Date:     date,
+		})
+	}
+	sort.Slice(cs, func(i, j int) bool { return cs[i].ID < cs[j].ID })
+	return cs, nil
+}
+
+type JSONRates struct {
+	Date   string                `json:"Date"`
+	Valute map[string]JSONValute `json:"Valute"`
+}
+
+type JSONValute struct {
+	ID       string      `json:"ID"`
+	NumCode  json.Number `json:"NumCode"`
+	CharCode string      `json:"CharCode"`
+	Nominal  int         `json:"Nominal"`
+	Name     string      `json:"Name"`
+	Value    json.Number `json:"Value"`
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONExtract(t *testing.T) {
+	f, err := os.Open("testdata/daily_json.js")
+	require.Nil(t, err)
+	defer f.Close()
+
+	cs, err := JSONExtract(f)
+	require.Nil(t, err)
+	require.Len(t, cs, 2)
+	require.Equal(t, "R01235", cs[0].ID)
+	require.Equal(t, 840, cs[0].NumCode)
+	require.Equal(t, "66.3274", cs[0].Value.String())
+	require.Equal(t, 100, cs[1].Nominal)
+	for _, c := range cs {
+		require.Equal(t, testRatesDate, c.Date)
+		require.Equal(t, CBRBase, c.Base)
+	}
+
+	for _, value := range []string{"0", "-66.3274"} {
+		_, err = JSONExtract(strings.NewReader(`{"Date":"2020-03-03T11:30:00+03:00","Valute":{"USD":{"ID":"R01235","CharCode":"USD","Nominal":1,"Value":` + value + `}}}`))
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), ErrJSON)
+	}
+}
+
+func TestJSONClient_LoadOnDate(t *testing.T) {
+	fixture, err := ioutil.ReadFile("testdata/daily_json.js")
+	require.Nil(t, err)
+
+	var path string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Write(fixture) //nolint:errcheck
+	}))
+	defer ts.Close()
+
+	client, err := NewProvider(ProviderConfig{
+		Type:       JSONProvider,
+		Source:     ts.URL + "/daily_json.js",
+		DateSource: ts.URL + "/archive/{yyyy}/{mm}/{dd}/daily_json.js",
+		Timeout:    5,
+	})
+	require.Nil(t, err)
+	cs, err := client.LoadOnDate(context.Background(), testRatesDate)
+	require.Nil(t, err)
+	require.Equal(t, "/archive/2020/03/03/daily_json.js", path)
+	require.Len(t, cs, 2)
+	require.Equal(t, JSONProvider, cs[0].Provider)
+}
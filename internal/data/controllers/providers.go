@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/redselig/currencier/internal/domain/entity"
+)
+
+const (
+	CBRProvider  = "cbr"
+	JSONProvider = "json"
+
+	// CBRBase is the currency the CBR sets its rates to.
+	CBRBase = "RUB"
+
+	ErrProvider = "can't create rates provider %v"
+)
+
+// ProviderConfig describes a rates source.
+type ProviderConfig struct {
+	// Name marks the rates loaded from the source, Type by default.
+	Name string
+	// Type selects the registered provider implementation.
+	Type string
+	// Source is the url of the latest rates.
+	Source string
+	// DateSource is the url of the rates on a date with {yyyy}, {mm} and {dd} placeholders,
+	// only used by providers which can't derive it from Source.
+	DateSource string
+	// Timeout of a request in seconds.
+	Timeout time.Duration
+}
+
+// ProviderFactory creates a rates provider of the registered type.
+type ProviderFactory func(cfg ProviderConfig) (entity.CurrencyExternalRepository, error)
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]ProviderFactory{}
+)
+
+func init() {
+	RegisterProvider(CBRProvider, func(cfg ProviderConfig) (entity.CurrencyExternalRepository, error) {
+		return NewHTTPClient(cfg.Name, cfg.Source, cfg.Timeout), nil
+	})
+	RegisterProvider(ECBProvider, func(cfg ProviderConfig) (entity.CurrencyExternalRepository, error) {
+		return NewECBClient(cfg.Name, cfg.Source, cfg.DateSource, cfg.Timeout), nil
+	})
+	RegisterProvider(JSONProvider, func(cfg ProviderConfig) (entity.CurrencyExternalRepository, error) {
+		return NewJSONClient(cfg.Name, cfg.Source, cfg.DateSource, cfg.Timeout), nil
+	})
+}
+
+// RegisterProvider makes the provider type available for NewProvider, it replaces a provider of the same type.
+func RegisterProvider(typ string, factory ProviderFactory) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[typ] = factory
+}
+
+// NewProvider creates a rates provider of the registered cfg.Type.
+func NewProvider(cfg ProviderConfig) (entity.CurrencyExternalRepository, error) {
+	providersMu.RLock()
+	factory, ok := providers[cfg.Type]
+	providersMu.RUnlock()
+	if !ok {
+		return nil, errors.Errorf(ErrProvider+": unknown type %v", cfg.Name, cfg.Type)
+	}
+	if cfg.Name == "" {
+		cfg.Name = cfg.Type
+	}
+	p, err := factory(cfg)
+	if err != nil {
+		return nil, errors.Wrapf(err, ErrProvider, cfg.Name)
+	}
+	return p, nil
+}
@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/redselig/currencier/internal/domain/entity"
+	"github.com/redselig/currencier/internal/domain/usecase"
+)
+
+const (
+	ErrCircuitOpen = "rates provider %v is down, circuit breaker is open"
+	ErrStatus      = "unexpected response status %v"
+
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// BreakerStates are all the states of the circuit breaker.
+var BreakerStates = []string{BreakerClosed, BreakerOpen, BreakerHalfOpen}
+
+// RetryConfig sets how loads failed with transient errors are repeated.
+type RetryConfig struct {
+	// Attempts is the number of tries of a load, retries are disabled if it's less than 2.
+	Attempts int
+	// Delay is the pause before the first retry, it's doubled for every next one up to MaxDelay.
+	Delay    time.Duration
+	MaxDelay time.Duration
+}
+
+// BreakerConfig sets when a provider is considered down.
+type BreakerConfig struct {
+	// Failures is the number of consecutive failed loads which opens the breaker, 0 disables the breaker.
+	Failures int
+	// Timeout is how long the open breaker rejects loads before it lets a trial one through.
+	Timeout time.Duration
+}
+
+var _ entity.CurrencyExternalRepository = (*ResilientProvider)(nil)
+
+// ResilientProvider retries transient failures of the wrapped provider with exponential backoff and jitter,
+// and stops calling it for a while after too many failed loads in a row.
+type ResilientProvider struct {
+	name     string
+	provider entity.CurrencyExternalRepository
+	retry    RetryConfig
+	breaker  BreakerConfig
+	logger   usecase.Logger
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+}
+
+// NewResilientProvider wraps provider, name is used in logs and errors.
+func NewResilientProvider(name string, provider entity.CurrencyExternalRepository, retry RetryConfig, breaker BreakerConfig, logger usecase.Logger) *ResilientProvider {
+	return &ResilientProvider{
+		name:     name,
+		provider: provider,
+		retry:    retry,
+		breaker:  breaker,
+		logger:   logger,
+		state:    BreakerClosed,
+	}
+}
+
+func (p *ResilientProvider) Load(ctx context.Context) ([]*entity.Currency, error) {
+	return p.load(ctx, p.provider.Load)
+}
+
+func (p *ResilientProvider) LoadOnDate(ctx context.Context, date time.Time) ([]*entity.Currency, error) {
+	return p.load(ctx, func(ctx context.Context) ([]*entity.Currency, error) {
+		return p.provider.LoadOnDate(ctx, date)
+	})
+}
+
+// Name returns the name of the wrapped provider.
+func (p *ResilientProvider) Name() string {
+	return p.name
+}
+
+// State returns the state of the circuit breaker: closed, open or half-open.
+func (p *ResilientProvider) State() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state
+}
+
+func (p *ResilientProvider) load(ctx context.Context, load func(ctx context.Context) ([]*entity.Currency, error)) ([]*entity.Currency, error) {
+	trial, err := p.allow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cs, err := p.withRetry(ctx, load)
+	if err != nil && ctx.Err() != nil {
+		// cancelled loads tell nothing about the provider
+		if trial {
+			p.reopen(ctx)
+		}
+		return cs, err
+	}
+	p.done(ctx, err)
+	return cs, err
+}
+
+func (p *ResilientProvider) withRetry(ctx context.Context, load func(ctx context.Context) ([]*entity.Currency, error)) ([]*entity.Currency, error) {
+	delay := p.retry.Delay
+	for attempt := 1; ; attempt++ {
+		cs, err := load(ctx)
+		if err == nil || attempt >= p.retry.Attempts || !IsTransient(err) {
+			return cs, err
+		}
+		wait := jitter(delay)
+		p.logger.Warn(ctx, "rates provider attempt failed, retrying",
+			usecase.String("provider", p.name), usecase.Int("attempt", attempt), usecase.Duration("retry_in", wait), usecase.Err(err))
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrap(err, ctx.Err().Error())
+		case <-time.After(wait):
+		}
+		delay *= 2
+		if p.retry.MaxDelay > 0 && delay > p.retry.MaxDelay {
+			delay = p.retry.MaxDelay
+		}
+	}
+}
+
+// allow rejects loads while the breaker is open and lets a single trial one through after the timeout,
+// the other loads are rejected until the trial one is done.
+func (p *ResilientProvider) allow(ctx context.Context) (trial bool, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case p.state == BreakerClosed:
+		return false, nil
+	case p.state == BreakerHalfOpen || time.Since(p.openedAt) < p.breaker.Timeout:
+		return false, errors.Errorf(ErrCircuitOpen, p.name)
+	}
+	p.state = BreakerHalfOpen
+	p.logger.Info(ctx, "circuit breaker of rates provider is half-open, trying to load", usecase.String("provider", p.name))
+	return true, nil
+}
+
+// reopen returns the breaker to open after its trial load is cancelled, so the next load is the trial one.
+func (p *ResilientProvider) reopen(ctx context.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state == BreakerHalfOpen {
+		p.state = BreakerOpen
+		p.logger.Info(ctx, "trial load of rates provider is cancelled, circuit breaker is open", usecase.String("provider", p.name))
+	}
+}
+
+// done counts the failed loads in a row and switches the breaker state.
+func (p *ResilientProvider) done(ctx context.Context, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err == nil {
+		if p.state != BreakerClosed {
+			p.logger.Info(ctx, "circuit breaker of rates provider is closed", usecase.String("provider", p.name))
+		}
+		p.state = BreakerClosed
+		p.failures = 0
+		return
+	}
+	if p.breaker.Failures < 1 {
+		return
+	}
+	p.failures++
+	if p.state == BreakerHalfOpen || p.failures >= p.breaker.Failures {
+		p.state = BreakerOpen
+		p.openedAt = time.Now()
+		p.logger.Warn(ctx, "circuit breaker of rates provider is open", usecase.String("provider", p.name),
+			usecase.Duration("timeout", p.breaker.Timeout), usecase.Int("failures", p.failures), usecase.Err(err))
+	}
+}
+
+// jitter returns a random duration between the half of d and d, so replicas don't retry at once.
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+type transientError struct {
+	error
+}
+
+func (e transientError) Unwrap() error {
+	return e.error
+}
+
+// IsTransient tells if the load may succeed when repeated: server errors, throttling, timeouts
+// and truncated or malformed documents which are error pages served instead of rates.
+func IsTransient(err error) bool {
+	var te transientError
+	if errors.As(err, &te) {
+		return true
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var xe *xml.SyntaxError
+	var je *json.SyntaxError
+	return errors.As(err, &xe) || errors.As(err, &je)
+}
+
+// checkStatus returns an error for unsuccessful responses, server errors and throttling are transient.
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	err := errors.Errorf(ErrStatus, resp.Status)
+	if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+		return transientError{err}
+	}
+	return err
+}
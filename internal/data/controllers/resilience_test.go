@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redselig/currencier/internal/domain/entity"
+	"github.com/redselig/currencier/internal/mocks"
+)
+
+func TestResilientProvider(t *testing.T) {
+	fixture, err := ioutil.ReadFile("testdata/XML_daily.xml")
+	require.Nil(t, err)
+
+	var calls, failures int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		switch {
+		case r.URL.Path == "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case atomic.AddInt32(&failures, -1) >= 0:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.URL.Path == "/truncated":
+			w.Write(fixture[:len(fixture)/2]) //nolint:errcheck
+		default:
+			w.Write(fixture) //nolint:errcheck
+		}
+	}))
+	defer ts.Close()
+
+	retry := RetryConfig{Attempts: 3, Delay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+	breaker := BreakerConfig{Failures: 2, Timeout: 50 * time.Millisecond}
+	newProvider := func(path string) *ResilientProvider {
+		return NewResilientProvider(CBRProvider, NewHTTPClient(CBRProvider, ts.URL+path, 5), retry, breaker, mocks.NewMockLogger())
+	}
+	ctx := context.Background()
+
+	t.Run("retry server errors", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		atomic.StoreInt32(&failures, 2)
+		cs, err := newProvider("/daily").Load(ctx)
+		require.Nil(t, err)
+		require.Len(t, cs, 3)
+		require.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+	t.Run("don't retry client errors", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		_, err := newProvider("/missing").Load(ctx)
+		require.NotNil(t, err)
+		require.False(t, IsTransient(err))
+		require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+	t.Run("retry truncated xml", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		atomic.StoreInt32(&failures, 0)
+		_, err := newProvider("/truncated").Load(ctx)
+		require.NotNil(t, err)
+		require.True(t, IsTransient(err))
+		require.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+	t.Run("circuit breaker", func(t *testing.T) {
+		p := newProvider("/daily")
+		atomic.StoreInt32(&failures, 6)
+		for i := 0; i < breaker.Failures; i++ {
+			_, err := p.Load(ctx)
+			require.NotNil(t, err)
+		}
+		require.Equal(t, BreakerOpen, p.State())
+
+		atomic.StoreInt32(&calls, 0)
+		_, err := p.Load(ctx)
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "circuit breaker is open")
+		require.Equal(t, int32(0), atomic.LoadInt32(&calls))
+
+		time.Sleep(breaker.Timeout)
+		_, err = p.Load(ctx)
+		require.Nil(t, err)
+		require.Equal(t, BreakerClosed, p.State())
+	})
+}
+
+type funcProvider func(ctx context.Context) ([]*entity.Currency, error)
+
+func (f funcProvider) Load(ctx context.Context) ([]*entity.Currency, error) {
+	return f(ctx)
+}
+
+func (f funcProvider) LoadOnDate(ctx context.Context, date time.Time) ([]*entity.Currency, error) {
+	return f(ctx)
+}
+
+func TestResilientProvider_HalfOpen(t *testing.T) {
+	var (
+		fail    = errors.New("down")
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+	load := func(ctx context.Context) ([]*entity.Currency, error) {
+		return nil, fail
+	}
+	breaker := BreakerConfig{Failures: 1, Timeout: 10 * time.Millisecond}
+	p := NewResilientProvider(CBRProvider, funcProvider(func(ctx context.Context) ([]*entity.Currency, error) {
+		return load(ctx)
+	}), RetryConfig{}, breaker, mocks.NewMockLogger())
+	blocking := func(ctx context.Context) ([]*entity.Currency, error) {
+		started <- struct{}{}
+		select {
+		case <-release:
+			return []*entity.Currency{{ID: "R01235"}}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	ctx := context.Background()
+
+	t.Run("single trial load", func(t *testing.T) {
+		_, err := p.Load(ctx)
+		require.Equal(t, fail, err)
+		require.Equal(t, BreakerOpen, p.State())
+		time.Sleep(breaker.Timeout)
+
+		load = blocking
+		done := make(chan error)
+		go func() {
+			_, err := p.Load(ctx)
+			done <- err
+		}()
+		<-started
+		require.Equal(t, BreakerHalfOpen, p.State())
+		_, err = p.Load(ctx)
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "circuit breaker is open")
+
+		close(release)
+		require.Nil(t, <-done)
+		require.Equal(t, BreakerClosed, p.State())
+	})
+	t.Run("cancelled trial load", func(t *testing.T) {
+		load = func(ctx context.Context) ([]*entity.Currency, error) {
+			return nil, fail
+		}
+		_, err := p.Load(ctx)
+		require.Equal(t, fail, err)
+		time.Sleep(breaker.Timeout)
+
+		release = make(chan struct{})
+		load = blocking
+		cctx, cancel := context.WithCancel(ctx)
+		done := make(chan error)
+		go func() {
+			_, err := p.Load(cctx)
+			done <- err
+		}()
+		<-started
+		cancel()
+		require.NotNil(t, <-done)
+		require.Equal(t, BreakerOpen, p.State())
+
+		load = func(ctx context.Context) ([]*entity.Currency, error) {
+			return []*entity.Currency{{ID: "R01235"}}, nil
+		}
+		_, err = p.Load(ctx)
+		require.Nil(t, err)
+		require.Equal(t, BreakerClosed, p.State())
+	})
+}
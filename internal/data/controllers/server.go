@@ -3,67 +3,192 @@ package controllers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
 
+	"github.com/redselig/currencier/internal/data/metrics"
+	"github.com/redselig/currencier/internal/domain/entity"
 	"github.com/redselig/currencier/internal/domain/usecase"
 	"github.com/redselig/currencier/internal/util"
 )
 
 const (
-	ErrID = "must be id in query"
+	ErrID     = "must be id in query"
+	ErrDate   = "date must be in format YYYY-MM-DD"
+	ErrConv   = "must be from, to and amount in query"
+	ErrAmount = "amount must be a decimal number"
+	ErrInt    = "%v must be an integer"
+	ErrNeg    = "%v must not be negative"
 )
 
+// ErrorResponse is the body of failed requests, Code is one of usecase error kinds.
+type ErrorResponse struct {
+	Code      string            `json:"code"`
+	Message   string            `json:"message"`
+	RequestID string            `json:"request_id"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
 type HTTPServer struct {
 	logger     usecase.Logger
 	server     *http.Server
 	currencier usecase.Currencier
+	metrics    *metrics.Metrics
+	health     Health
 }
 
-func NewHttpServer(addr string, logger usecase.Logger, currencier usecase.Currencier) *HTTPServer {
+// NewHttpServer creates server of the API, with nil currencier it serves the metrics and the probes only
+// (e.g. for the updater process), metrics may be nil too.
+func NewHttpServer(addr string, logger usecase.Logger, currencier usecase.Currencier, metrics *metrics.Metrics, health Health) *HTTPServer {
 	server := &http.Server{Addr: addr}
 	return &HTTPServer{
 		server:     server,
 		logger:     logger,
 		currencier: currencier,
+		metrics:    metrics,
+		health:     health,
 	}
 }
 
 func (s *HTTPServer) Serve() error {
-	s.logger.Log(context.Background(), "starting http server on address [%v]", s.server.Addr)
+	s.logger.Info(context.Background(), "starting http server", usecase.String("addr", s.server.Addr))
 
-	router := mux.NewRouter()
+	s.server.Handler = s.handler()
+	if err := s.server.ListenAndServe(); err != http.ErrServerClosed {
+		return errors.Wrapf(err, "can't start listen address [%v]", s.server.Addr)
+	}
+	return nil
+}
 
-	router.HandleFunc("/", s.getCurrencies).Methods(http.MethodGet)
-	router.HandleFunc("/currencies", s.getCurrencies).Methods(http.MethodGet)
-	router.HandleFunc("/lazycurrencies", s.getLazyCurrencies).Methods(http.MethodGet)
+func (s *HTTPServer) handler() http.Handler {
+	router := mux.NewRouter()
 
-	router.HandleFunc("/currency/{id}", s.getCurrency).Methods(http.MethodGet) //todo: should be /currencies/{id}
+	router.HandleFunc("/healthz", s.healthz).Methods(http.MethodGet)
+	router.HandleFunc("/readyz", s.readyz).Methods(http.MethodGet)
+	if s.metrics != nil {
+		router.Handle("/metrics", s.metrics.Handler()).Methods(http.MethodGet)
+		router.Use(s.metricsMiddleware)
+	}
+	if s.currencier != nil {
+		// the probes and scrapes aren't traced
+		api := router.NewRoute().Subrouter()
+		api.Use(s.tracingMiddleware)
 
-	handler := s.accessLogMiddleware(router)
-	handler = s.panicMiddleware(handler)
-	s.server.Handler = handler
+		api.HandleFunc("/", s.getCurrencies).Methods(http.MethodGet)
+		api.HandleFunc("/currencies", s.getCurrencies).Methods(http.MethodGet)
+		api.HandleFunc("/lazycurrencies", s.getLazyCurrencies).Methods(http.MethodGet)
 
-	if err := s.server.ListenAndServe(); err != http.ErrServerClosed {
-		return errors.Wrapf(err, "can't start listen address [%v]", s.server.Addr)
+		api.HandleFunc("/currency/{id}", s.getCurrency).Methods(http.MethodGet) //todo: should be /currencies/{id}
+		api.HandleFunc("/history/{id}", s.getHistory).Methods(http.MethodGet)
+		api.HandleFunc("/convert", s.convert).Methods(http.MethodGet)
 	}
-	return nil
+
+	// the panics are recovered inside to answer and log them with the request id
+	handler := s.panicMiddleware(router)
+	return s.accessLogMiddleware(handler)
 }
 
 func (s *HTTPServer) getCurrency(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, ok := vars["id"]
 	if !ok || id == "" {
-		s.httpError(r.Context(), w, ErrID, http.StatusBadRequest)
+		s.badRequest(r.Context(), w, ErrID, "id", id)
+		return
+	}
+	date, ok := r.URL.Query()["date"]
+	if !ok || len(date) != 1 {
+		c, err := s.currencier.GetCurrencyBuID(r.Context(), id)
+		if err != nil {
+			s.httpError(r.Context(), w, err)
+			return
+		}
+		s.httpAnswer(w, c, http.StatusOK)
+		return
+	}
+	tDate, err := time.Parse(util.LayoutDate, date[0])
+	if err != nil {
+		s.badRequest(r.Context(), w, ErrDate, "date", date[0])
 		return
 	}
-	c, err := s.currencier.GetCurrencyBuID(r.Context(), id)
+	c, err := s.currencier.GetCurrencyOnDate(r.Context(), id, tDate)
 	if err != nil {
-		s.httpError(r.Context(), w, err.Error(), http.StatusBadRequest)
+		s.httpError(r.Context(), w, err)
+		return
+	}
+	s.httpAnswer(w, c, http.StatusOK)
+}
+
+func (s *HTTPServer) getHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok || id == "" {
+		s.badRequest(r.Context(), w, ErrID, "id", id)
+		return
+	}
+	query := r.URL.Query()
+
+	now := time.Now().UTC()
+	from, to := now.AddDate(0, -1, 0), now
+	if v, ok := query["from"]; ok && len(v) == 1 {
+		t, err := time.Parse(util.LayoutDate, v[0])
+		if err != nil {
+			s.badRequest(r.Context(), w, ErrDate, "from", v[0])
+			return
+		}
+		from = t
+	}
+	if v, ok := query["to"]; ok && len(v) == 1 {
+		t, err := time.Parse(util.LayoutDate, v[0])
+		if err != nil {
+			s.badRequest(r.Context(), w, ErrDate, "to", v[0])
+			return
+		}
+		to = t
+	}
+
+	cs, err := s.currencier.GetCurrencyHistory(r.Context(), id, from, to)
+	if err != nil {
+		s.httpError(r.Context(), w, err)
+		return
+	}
+	if cs == nil {
+		// known currency without rates in the range
+		cs = []*entity.Currency{}
+	}
+	s.httpAnswer(w, cs, http.StatusOK)
+}
+
+func (s *HTTPServer) convert(w http.ResponseWriter, r *http.Request) {
+	vars := r.URL.Query()
+
+	from, to, amount := vars.Get("from"), vars.Get("to"), vars.Get("amount")
+	if from == "" || to == "" || amount == "" {
+		s.badRequest(r.Context(), w, ErrConv, "", "")
+		return
+	}
+	dAmount, err := decimal.NewFromString(amount)
+	if err != nil {
+		s.badRequest(r.Context(), w, ErrAmount, "amount", amount)
+		return
+	}
+	var tDate time.Time
+	if date := vars.Get("date"); date != "" {
+		tDate, err = time.Parse(util.LayoutDate, date)
+		if err != nil {
+			s.badRequest(r.Context(), w, ErrDate, "date", date)
+			return
+		}
+	}
+
+	c, err := s.currencier.Convert(r.Context(), from, to, dAmount, tDate)
+	if err != nil {
+		s.httpError(r.Context(), w, err)
 		return
 	}
 	s.httpAnswer(w, c, http.StatusOK)
@@ -82,18 +207,26 @@ func (s *HTTPServer) getCurrencies(w http.ResponseWriter, r *http.Request) {
 	}
 	iLimit, err := strconv.Atoi(limit[0])
 	if err != nil {
-		s.httpError(r.Context(), w, err.Error(), http.StatusBadRequest)
+		s.badRequest(r.Context(), w, fmt.Sprintf(ErrInt, "limit"), "limit", limit[0])
+		return
+	}
+	if iLimit < 0 {
+		s.badRequest(r.Context(), w, fmt.Sprintf(ErrNeg, "limit"), "limit", limit[0])
 		return
 	}
 	iOffset, err := strconv.Atoi(offset[0])
 	if err != nil {
-		s.httpError(r.Context(), w, err.Error(), http.StatusBadRequest)
+		s.badRequest(r.Context(), w, fmt.Sprintf(ErrInt, "offset"), "offset", offset[0])
+		return
+	}
+	if iOffset < 0 {
+		s.badRequest(r.Context(), w, fmt.Sprintf(ErrNeg, "offset"), "offset", offset[0])
 		return
 	}
 
-	c, err := s.currencier.GetCurrenciesPage(r.Context(), iLimit, iOffset)
+	c, err := s.currencier.GetCurrenciesPage(r.Context(), iLimit, iOffset, vars.Get("base"))
 	if err != nil {
-		s.httpError(r.Context(), w, err.Error(), http.StatusBadRequest)
+		s.httpError(r.Context(), w, err)
 		return
 	}
 	s.httpAnswer(w, c, http.StatusOK)
@@ -112,12 +245,17 @@ func (s *HTTPServer) getLazyCurrencies(w http.ResponseWriter, r *http.Request) {
 	}
 	iLimit, err := strconv.Atoi(limit[0])
 	if err != nil {
-		s.httpError(r.Context(), w, err.Error(), http.StatusBadRequest)
+		s.badRequest(r.Context(), w, fmt.Sprintf(ErrInt, "limit"), "limit", limit[0])
+		return
+	}
+	if iLimit < 0 {
+		s.badRequest(r.Context(), w, fmt.Sprintf(ErrNeg, "limit"), "limit", limit[0])
+		return
 	}
 
-	c, err := s.currencier.GetCurrenciesLazy(r.Context(), iLimit, lastID[0])
+	c, err := s.currencier.GetCurrenciesLazy(r.Context(), iLimit, lastID[0], vars.Get("base"))
 	if err != nil {
-		s.httpError(r.Context(), w, err.Error(), http.StatusBadRequest)
+		s.httpError(r.Context(), w, err)
 		return
 	}
 	s.httpAnswer(w, c, http.StatusOK)
@@ -125,50 +263,116 @@ func (s *HTTPServer) getLazyCurrencies(w http.ResponseWriter, r *http.Request) {
 
 func (s *HTTPServer) StopServe() {
 	ctx := context.Background()
-	s.logger.Log(ctx, "stopping http server")
-	defer s.logger.Log(ctx, "http server stopped")
+	s.logger.Info(ctx, "stopping http server")
+	defer s.logger.Info(ctx, "http server stopped")
 	if s.server == nil {
-		s.logger.Log(ctx, "http server is nil")
+		s.logger.Warn(ctx, "http server is nil")
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 	if err := s.server.Shutdown(ctx); err != nil {
-		s.logger.Log(ctx, "can't stop http server with error: %v", err)
+		s.logger.Error(ctx, "can't stop http server", usecase.Err(err))
 	}
 }
 
+// accessLogMiddleware logs the requests, it takes the request id from the X-Request-ID header
+// (a new one is generated if it's missing or invalid) and returns it in the same header.
 func (s *HTTPServer) accessLogMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		ctx := util.SetRequestID(r.Context())
+		ctx := util.WithRequestID(r.Context(), r.Header.Get(util.RequestIDHeader))
+		w.Header().Set(util.RequestIDHeader, util.GetRequestID(ctx))
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		s.logger.Info(ctx, "request",
+			usecase.String("remote_addr", r.RemoteAddr),
+			usecase.String("method", r.Method),
+			usecase.String("path", r.URL.Path),
+			usecase.Int("status", sw.status),
+			usecase.Duration("latency", time.Since(start)),
+			usecase.Int64("bytes", sw.bytes))
+	})
+}
+
+// metricsMiddleware counts requests by the matched route, so it's used by the router.
+func (s *HTTPServer) metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
 
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(sw, r)
 
-		latency := time.Since(start)
-		s.logRequest(ctx, r.RemoteAddr, start.Format(util.LayoutISO), r.Method, r.URL.Path, latency)
+		route, _ := mux.CurrentRoute(r).GetPathTemplate()
+		s.metrics.ObserveRequest(route, r.Method, sw.status, time.Since(start))
 	})
 }
 
+// statusWriter remembers the status and counts the body bytes of the response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
 func (s *HTTPServer) panicMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				s.httpError(r.Context(), w, "Internal server error", http.StatusInternalServerError)
+				s.httpError(r.Context(), w, errors.Errorf("panic: %v", err))
 			}
 		}()
 		next.ServeHTTP(w, r)
 	})
 }
 
-func (s *HTTPServer) logRequest(ctx context.Context, remoteAddr, start, method, path string, latency time.Duration) {
-	s.logger.Log(ctx, "%s [%s] %s %s [%s]", remoteAddr, start, method, path, latency)
+// httpError answers with the error envelope and the status of the use case error kind,
+// the cause of the error is only logged, the errors of the clients at debug level.
+func (s *HTTPServer) httpError(ctx context.Context, w http.ResponseWriter, err error) {
+	e := usecase.AsError(err)
+	code := http.StatusInternalServerError
+	log := s.logger.Error
+	switch e.Kind {
+	case usecase.KindInvalid:
+		code = http.StatusBadRequest
+		log = s.logger.Debug
+	case usecase.KindNotFound:
+		code = http.StatusNotFound
+		log = s.logger.Debug
+	case usecase.KindUnavailable:
+		code = http.StatusServiceUnavailable
+		log = s.logger.Warn
+	}
+	log(ctx, "request failed", usecase.String("kind", string(e.Kind)), usecase.Err(err))
+	s.httpAnswer(w, ErrorResponse{
+		Code:      string(e.Kind),
+		Message:   e.Message,
+		RequestID: util.GetRequestID(ctx),
+		Details:   e.Details,
+	}, code)
 }
 
-func (s *HTTPServer) httpError(ctx context.Context, w http.ResponseWriter, error string, code int) {
-	s.logger.Log(ctx, error)
-	http.Error(w, error, code)
+// badRequest answers with invalid argument error, the wrong value of the param is its detail.
+func (s *HTTPServer) badRequest(ctx context.Context, w http.ResponseWriter, message, param, value string) {
+	e := usecase.NewError(usecase.KindInvalid, nil, message)
+	if param != "" {
+		e.WithDetail(param, value)
+	}
+	s.httpError(ctx, w, e)
 }
 
 func (s *HTTPServer) httpAnswer(w http.ResponseWriter, msg interface{}, code int) {
@@ -176,6 +380,7 @@ func (s *HTTPServer) httpAnswer(w http.ResponseWriter, msg interface{}, code int
 	if err != nil {
 		code = http.StatusInternalServerError
 	}
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
 	w.Write(jmsg) //nolint:errcheck
 }
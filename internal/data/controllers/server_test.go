@@ -1,24 +1,33 @@
 package controllers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
 
+	"github.com/redselig/currencier/internal/data/logger/zerologger"
+	"github.com/redselig/currencier/internal/data/metrics"
+	"github.com/redselig/currencier/internal/data/repository/memory"
 	"github.com/redselig/currencier/internal/domain/entity"
 	"github.com/redselig/currencier/internal/domain/usecase"
 	"github.com/redselig/currencier/internal/mocks"
+	"github.com/redselig/currencier/internal/util"
 )
 
 var (
 	testID       = "R01020A"
 	testName     = "Азербайджанский манат"
-	testRate     = 44.7113
+	testRate     = decimal.RequireFromString("44.7113")
 	testCurrency = entity.Currency{
 		ID:       testID,
 		NumCode:  0,
@@ -33,8 +42,8 @@ func TestHTTPServer_Serve(t *testing.T) {
 	repo := mocks.NewMockRepo(&testCurrency)
 	logger := mocks.NewMockLogger()
 
-	currensier := usecase.NewCurrencierInteractor(nil, repo)
-	server := NewHttpServer("", logger, currensier)
+	currensier := usecase.NewCurrencierInteractor(nil, repo, usecase.DefaultScale, logger)
+	server := NewHttpServer("", logger, currensier, nil, Health{})
 	testCurrencyAnswer, err := json.Marshal(testCurrency)
 	require.Nil(t, err)
 	testCurrenciesAnswer, err := json.Marshal([]*entity.Currency{&testCurrency})
@@ -55,7 +64,7 @@ func TestHTTPServer_Serve(t *testing.T) {
 			{"bad get",
 				map[string]string{},
 				400,
-				ErrID + "\n",
+				errorBody(t, usecase.KindInvalid, ErrID, map[string]string{"id": ""}),
 			},
 		}
 		for _, tcase := range tCases {
@@ -90,7 +99,17 @@ func TestHTTPServer_Serve(t *testing.T) {
 			{"bad get Currencies",
 				httptest.NewRequest(http.MethodGet, "/currencies?limit=bad_value&offset=5", nil),
 				400,
-				"strconv.Atoi: parsing \"bad_value\": invalid syntax\n",
+				errorBody(t, usecase.KindInvalid, "limit must be an integer", map[string]string{"limit": "bad_value"}),
+			},
+			{"negative limit",
+				httptest.NewRequest(http.MethodGet, "/currencies?limit=-1", nil),
+				400,
+				errorBody(t, usecase.KindInvalid, "limit must not be negative", map[string]string{"limit": "-1"}),
+			},
+			{"negative offset",
+				httptest.NewRequest(http.MethodGet, "/currencies?limit=10&offset=-5", nil),
+				400,
+				errorBody(t, usecase.KindInvalid, "offset must not be negative", map[string]string{"offset": "-5"}),
 			},
 		}
 		for _, tcase := range tCases {
@@ -109,4 +128,239 @@ func TestHTTPServer_Serve(t *testing.T) {
 
 		}
 	})
+	t.Run("GET convert", func(t *testing.T) {
+		testConversionAnswer, err := json.Marshal(entity.Conversion{
+			From:   testID,
+			To:     usecase.BaseCurrency,
+			Amount: decimal.New(100, 0),
+			Rate:   testRate,
+			Result: decimal.RequireFromString("4471.13"),
+		})
+		require.Nil(t, err)
+		tCases := []struct {
+			title string
+			req   *http.Request
+			code  int
+			body  string
+		}{
+			{"good convert",
+				httptest.NewRequest(http.MethodGet, "/convert?from="+testID+"&to=RUB&amount=100", nil),
+				200,
+				string(testConversionAnswer),
+			},
+			{"bad convert",
+				httptest.NewRequest(http.MethodGet, "/convert?from="+testID+"&amount=100", nil),
+				400,
+				errorBody(t, usecase.KindInvalid, ErrConv, nil),
+			},
+			{"bad amount",
+				httptest.NewRequest(http.MethodGet, "/convert?from="+testID+"&to=RUB&amount=1,5", nil),
+				400,
+				errorBody(t, usecase.KindInvalid, ErrAmount, map[string]string{"amount": "1,5"}),
+			},
+		}
+		for _, tcase := range tCases {
+			t.Run(tcase.title, func(t *testing.T) {
+				w := httptest.NewRecorder()
+
+				server.convert(w, tcase.req)
+				resp := w.Result()
+				body, err := ioutil.ReadAll(resp.Body)
+				require.Nil(t, err)
+				code := resp.StatusCode
+
+				require.Equal(t, tcase.code, code)
+				require.Equal(t, tcase.body, string(body))
+			})
+
+		}
+	})
+}
+
+func TestHTTPServer_NotFound(t *testing.T) {
+	logger := mocks.NewMockLogger()
+	// the rate without num code is stored with zero one
+	repo := memory.NewMemRepo()
+	require.Nil(t, repo.SetAll(context.Background(), []*entity.Currency{&testCurrency}))
+	server := NewHttpServer("", logger, usecase.NewCurrencierInteractor(nil, repo, usecase.DefaultScale, logger), nil, Health{})
+
+	tCases := []struct {
+		title   string
+		handler http.HandlerFunc
+		req     *http.Request
+		code    string
+	}{
+		{"currency",
+			server.getCurrency,
+			mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/currency/XXX", nil), map[string]string{"id": "XXX"}),
+			"XXX",
+		},
+		{"zero num code",
+			server.getCurrency,
+			mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/currency/0", nil), map[string]string{"id": "0"}),
+			"0",
+		},
+		{"convert",
+			server.convert,
+			httptest.NewRequest(http.MethodGet, "/convert?from=XXX&to=RUB&amount=1", nil),
+			"XXX",
+		},
+	}
+	for _, tcase := range tCases {
+		t.Run(tcase.title, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tcase.handler(w, tcase.req)
+			resp := w.Result()
+			body, err := ioutil.ReadAll(resp.Body)
+			require.Nil(t, err)
+
+			require.Equal(t, http.StatusNotFound, resp.StatusCode)
+			require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+			require.Equal(t, errorBody(t, usecase.KindNotFound, "unknown currency "+tcase.code, map[string]string{"currency": tcase.code}), string(body))
+		})
+	}
+}
+
+func TestHTTPServer_History(t *testing.T) {
+	logger := mocks.NewMockLogger()
+	repo := memory.NewMemRepo()
+	require.Nil(t, repo.SetAll(context.Background(), []*entity.Currency{&testCurrency}))
+	server := NewHttpServer("", logger, usecase.NewCurrencierInteractor(nil, repo, usecase.DefaultScale, logger), nil, Health{})
+
+	w := httptest.NewRecorder()
+	server.getHistory(w, mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/history/NOPE", nil), map[string]string{"id": "NOPE"}))
+	require.Equal(t, http.StatusNotFound, w.Code)
+	require.Equal(t, errorBody(t, usecase.KindNotFound, "unknown currency NOPE", map[string]string{"currency": "NOPE"}), w.Body.String())
+
+	w = httptest.NewRecorder()
+	server.getHistory(w, mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/history/"+testID+"?from=2000-01-01&to=2000-01-31", nil),
+		map[string]string{"id": testID}))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "[]", w.Body.String())
+}
+
+func TestHTTPServer_Metrics(t *testing.T) {
+	logger := mocks.NewMockLogger()
+	currencier := usecase.NewCurrencierInteractor(nil, mocks.NewMockRepo(&testCurrency), usecase.DefaultScale, logger)
+	handler := NewHttpServer("", logger, currencier, metrics.NewMetrics(), Health{}).handler()
+
+	for _, path := range []string{"/currency/" + testID, "/currency/" + testID, "/currencies?limit=x"} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	require.Contains(t, body, `currencier_http_requests_total{method="GET",route="/currency/{id}",status="200"} 2`)
+	require.Contains(t, body, `currencier_http_requests_total{method="GET",route="/currencies",status="400"} 1`)
+	require.Contains(t, body, `currencier_http_request_duration_seconds_count{method="GET",route="/currency/{id}",status="200"} 2`)
+}
+
+func TestHTTPServer_MetricsOnly(t *testing.T) {
+	handler := NewHttpServer("", mocks.NewMockLogger(), nil, metrics.NewMetrics(), Health{}).handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/currencies", nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestHTTPServer_RequestID(t *testing.T) {
+	logger := mocks.NewMockLogger()
+	currencier := usecase.NewCurrencierInteractor(nil, memory.NewMemRepo(), usecase.DefaultScale, logger)
+	handler := NewHttpServer("", logger, currencier, nil, Health{}).handler()
+
+	tCases := []struct {
+		title string
+		id    string
+		same  bool
+	}{
+		{"incoming", "gw-5f0c:42", true},
+		{"missing", "", false},
+		{"invalid", "id with spaces\r\n", false},
+		{"too long", strings.Repeat("a", 129), false},
+	}
+	for _, tcase := range tCases {
+		t.Run(tcase.title, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/currency/XXX", nil)
+			req.Header.Set(util.RequestIDHeader, tcase.id)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			id := w.Header().Get(util.RequestIDHeader)
+			require.NotEmpty(t, id)
+			require.Equal(t, tcase.same, id == tcase.id)
+			var body ErrorResponse
+			require.Nil(t, json.Unmarshal(w.Body.Bytes(), &body))
+			require.Equal(t, id, body.RequestID)
+		})
+	}
+}
+
+func TestHTTPServer_AccessLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger, err := zerologger.NewLogger(buf, zerologger.LevelInfo)
+	require.Nil(t, err)
+	currencier := usecase.NewCurrencierInteractor(nil, mocks.NewMockRepo(&testCurrency), usecase.DefaultScale, logger)
+	handler := NewHttpServer("", logger, currencier, nil, Health{}).handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/currency/"+testID, nil)
+	req.Header.Set(util.RequestIDHeader, "req-1")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	var rec map[string]interface{}
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &rec))
+	require.Equal(t, "request", rec["message"])
+	require.Equal(t, "info", rec["level"])
+	require.Equal(t, "req-1", rec[zerologger.RequestIDKey])
+	require.Equal(t, http.MethodGet, rec["method"])
+	require.Equal(t, "/currency/"+testID, rec["path"])
+	require.Equal(t, float64(http.StatusOK), rec["status"])
+	require.Equal(t, float64(w.Body.Len()), rec["bytes"])
+	require.Contains(t, rec, "latency")
+}
+
+func TestHTTPServer_Probes(t *testing.T) {
+	var dbErr error
+	health := Health{
+		Live: map[string]Check{"updater": func(ctx context.Context) error { return nil }},
+		Ready: map[string]Check{
+			"db":    func(ctx context.Context) error { return dbErr },
+			"rates": func(ctx context.Context) error { return nil },
+		},
+	}
+	handler := NewHttpServer("", mocks.NewMockLogger(), nil, nil, health).handler()
+	probe := func(path string) (int, string) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code, w.Body.String()
+	}
+
+	code, body := probe("/healthz")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, `{"status":"ok","checks":{"updater":"ok"}}`, body)
+	code, body = probe("/readyz")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, `{"status":"ok","checks":{"db":"ok","rates":"ok"}}`, body)
+
+	dbErr = errors.New("failed to connect to db: postgres://user:secret@db")
+	code, body = probe("/readyz")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, `{"status":"fail","checks":{"db":"fail","rates":"ok"}}`, body)
+	code, _ = probe("/healthz")
+	require.Equal(t, http.StatusOK, code)
+}
+
+func errorBody(t *testing.T, kind usecase.Kind, message string, details map[string]string) string {
+	body, err := json.Marshal(ErrorResponse{
+		Code:    string(kind),
+		Message: message,
+		Details: details,
+	})
+	require.Nil(t, err)
+	return string(body)
 }
@@ -0,0 +1,25 @@
+{
+    "Date": "2020-03-03T11:30:00+03:00",
+    "PreviousDate": "2020-02-29T11:30:00+03:00",
+    "Timestamp": "2020-03-02T20:00:00+03:00",
+    "Valute": {
+        "USD": {
+            "ID": "R01235",
+            "NumCode": "840",
+            "CharCode": "USD",
+            "Nominal": 1,
+            "Name": "Доллар США",
+            "Value": 66.3274,
+            "Previous": 66.9909
+        },
+        "JPY": {
+            "ID": "R01820",
+            "NumCode": "392",
+            "CharCode": "JPY",
+            "Nominal": 100,
+            "Name": "Японских иен",
+            "Value": 61.5012,
+            "Previous": 61.7403
+        }
+    }
+}
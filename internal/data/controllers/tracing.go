@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/gorilla/mux"
+
+	"github.com/redselig/currencier/internal/data/tracing"
+	"github.com/redselig/currencier/internal/domain/entity"
+)
+
+const (
+	tracerName = "github.com/redselig/currencier/internal/data/controllers"
+
+	providerKey = attribute.Key("currencier.provider")
+)
+
+// tracingMiddleware traces the requests continuing the trace of the caller's traceparent header,
+// it's used by the router to name spans by the matched route.
+func (s *HTTPServer) tracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, _ := mux.CurrentRoute(r).GetPathTemplate()
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.Start(ctx, tracerName, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest("", route, r)...))
+		defer span.End()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(sw.status)...)
+		if sw.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(sw.status))
+		}
+	})
+}
+
+// do sends the request to the provider in a client span and propagates the trace to it.
+func do(ctx context.Context, client *http.Client, provider string, req *http.Request) (*http.Response, error) {
+	ctx, span := tracing.Start(ctx, tracerName, "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(providerKey.String(provider)))
+	req = req.WithContext(ctx)
+	span.SetAttributes(semconv.HTTPClientAttributesFromHTTPRequest(req)...)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := client.Do(req)
+	if err == nil {
+		span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(resp.StatusCode)...)
+		span.SetStatus(semconv.SpanStatusFromHTTPStatusCode(resp.StatusCode))
+	}
+	tracing.End(span, err)
+	return resp, err
+}
+
+// parse traces parsing of the feed of the format.
+func parse(ctx context.Context, format string, extract func() ([]*entity.Currency, error)) ([]*entity.Currency, error) {
+	_, span := tracing.Start(ctx, tracerName, format+" parse")
+	cs, err := extract()
+	span.SetAttributes(attribute.Int("currencier.rates", len(cs)))
+	tracing.End(span, err)
+	return cs, err
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/redselig/currencier/internal/data/tracing"
+	"github.com/redselig/currencier/internal/domain/usecase"
+	"github.com/redselig/currencier/internal/mocks"
+)
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	var traceparent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		http.ServeFile(w, r, "testdata/XML_daily.xml")
+	}))
+	defer ts.Close()
+
+	logger := mocks.NewMockLogger()
+	currencier := usecase.NewCurrencierInteractor(nil, mocks.NewMockRepo(&testCurrency), usecase.DefaultScale, logger)
+	handler := NewHttpServer("", logger, currencier, nil, Health{}).handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/currency/"+testID, nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	server := spans[0]
+	require.Equal(t, "GET /currency/{id}", server.Name())
+	require.Equal(t, trace.SpanKindServer, server.SpanKind())
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	require.NotEmpty(t, spanAttribute(server, tracing.RequestIDKey))
+
+	ctx, root := otel.Tracer("test").Start(context.Background(), "update")
+	_, err := NewHTTPClient("cbr", ts.URL, 30).Load(ctx)
+	root.End()
+	require.Nil(t, err)
+
+	spans = recorder.Ended()[1:]
+	require.Len(t, spans, 3)
+	fetch, parse := spans[0], spans[1]
+	require.Equal(t, "HTTP GET", fetch.Name())
+	require.Equal(t, trace.SpanKindClient, fetch.SpanKind())
+	require.Equal(t, root.SpanContext().SpanID(), fetch.Parent().SpanID())
+	require.Contains(t, traceparent, fetch.SpanContext().SpanID().String())
+	require.Equal(t, "xml parse", parse.Name())
+	require.Equal(t, root.SpanContext().SpanID(), parse.Parent().SpanID())
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) string {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,89 @@
+package leader
+
+import (
+	"context"
+	"time"
+
+	"github.com/redselig/currencier/internal/domain/usecase"
+)
+
+// Lock is the leadership held by the replica.
+type Lock interface {
+	// Check returns error if the lock is lost.
+	Check(ctx context.Context) error
+	Unlock(ctx context.Context) error
+}
+
+// TryLock takes the leadership lock without waiting, it returns nil Lock if another replica holds it.
+type TryLock func(ctx context.Context) (Lock, error)
+
+// Elector runs the work on the one replica which holds the lock, the others try to take it every interval
+// and take over when the leader dies.
+type Elector struct {
+	name     string
+	tryLock  TryLock
+	interval time.Duration
+	logger   usecase.Logger
+}
+
+// NewElector creates elector of the work, name is used in logs.
+func NewElector(name string, tryLock TryLock, interval time.Duration, logger usecase.Logger) *Elector {
+	return &Elector{
+		name:     name,
+		tryLock:  tryLock,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Run runs the work while the replica is the leader until ctx is done,
+// the work's context is cancelled as soon as the leadership is lost.
+func (e *Elector) Run(ctx context.Context, work func(ctx context.Context)) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		lock, err := e.tryLock(ctx)
+		if err != nil {
+			e.logger.Error(ctx, "can't elect leader", usecase.String("elector", e.name), usecase.Err(err))
+		}
+		if lock != nil {
+			e.logger.Info(ctx, "replica is the leader", usecase.String("elector", e.name))
+			e.lead(ctx, lock, ticker.C, work)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lead runs the work and checks the lock on every tick, the lock is released when the work is stopped.
+func (e *Elector) lead(ctx context.Context, lock Lock, tick <-chan time.Time, work func(ctx context.Context)) {
+	workCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		work(workCtx)
+	}()
+
+	defer func() {
+		cancel()
+		<-done
+		if err := lock.Unlock(context.Background()); err != nil {
+			e.logger.Error(ctx, "can't release leadership", usecase.String("elector", e.name), usecase.Err(err))
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		case <-tick:
+			if err := lock.Check(ctx); err != nil && ctx.Err() == nil {
+				e.logger.Warn(ctx, "replica lost the leadership", usecase.String("elector", e.name), usecase.Err(err))
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/redselig/currencier/internal/mocks"
+)
+
+// testLock is a lock shared by the replicas of the test.
+type testLock struct {
+	mu     sync.Mutex
+	holder *testLease
+}
+
+type testLease struct {
+	lock *testLock
+	lost bool
+}
+
+func (l *testLock) tryLock(ctx context.Context) (Lock, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.holder != nil {
+		return nil, nil
+	}
+	l.holder = &testLease{lock: l}
+	return l.holder, nil
+}
+
+func (l *testLease) Check(ctx context.Context) error {
+	l.lock.mu.Lock()
+	defer l.lock.mu.Unlock()
+	if l.lost {
+		return errors.New("connection is lost")
+	}
+	return nil
+}
+
+func (l *testLease) Unlock(ctx context.Context) error {
+	l.lock.mu.Lock()
+	defer l.lock.mu.Unlock()
+	// the db keeps the lock of the lost session until it notices the session is dead
+	if l.lock.holder == l && !l.lost {
+		l.lock.holder = nil
+	}
+	return nil
+}
+
+func TestElector_Run(t *testing.T) {
+	lock := &testLock{}
+	var mu sync.Mutex
+	working := map[string]bool{}
+	work := func(name string) func(ctx context.Context) {
+		return func(ctx context.Context) {
+			mu.Lock()
+			working[name] = true
+			mu.Unlock()
+			<-ctx.Done()
+			mu.Lock()
+			working[name] = false
+			mu.Unlock()
+		}
+	}
+	leaders := func() (names []string) {
+		mu.Lock()
+		defer mu.Unlock()
+		for name, ok := range working {
+			if ok {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+
+	ctx := context.Background()
+	cancels := map[string]context.CancelFunc{}
+	wg := sync.WaitGroup{}
+	for _, name := range []string{"first", "second"} {
+		replicaCtx, cancel := context.WithCancel(ctx)
+		cancels[name] = cancel
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			NewElector(name, lock.tryLock, 10*time.Millisecond, mocks.NewMockLogger()).Run(replicaCtx, work(name))
+		}(name)
+	}
+
+	require.Eventually(t, func() bool { return len(leaders()) == 1 }, time.Second, 5*time.Millisecond)
+	first := leaders()[0]
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, []string{first}, leaders())
+
+	// the leader stops and the other replica takes over
+	cancels[first]()
+	require.Eventually(t, func() bool {
+		names := leaders()
+		return len(names) == 1 && names[0] != first
+	}, time.Second, 5*time.Millisecond)
+	second := leaders()[0]
+
+	// the work is stopped as soon as the lock is lost
+	lock.mu.Lock()
+	lock.holder.lost = true
+	lock.mu.Unlock()
+	require.Eventually(t, func() bool { return len(leaders()) == 0 }, time.Second, 5*time.Millisecond)
+
+	cancels[second]()
+	wg.Wait()
+	require.Empty(t, leaders())
+}
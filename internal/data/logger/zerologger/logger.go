@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
@@ -12,40 +13,92 @@ import (
 	"github.com/redselig/currencier/internal/util"
 )
 
+const (
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+
+	// RequestIDKey is the field the request id of the context is written to.
+	RequestIDKey = "request_id"
+)
+
 var _ usecase.Logger = (*Logger)(nil)
 
+// Logger writes json records, durations are written in milliseconds.
 type Logger struct {
-	logger  *zerolog.Logger
-	isDebug bool
+	logger *zerolog.Logger
+	stacks bool
+}
+
+// NewLogger creates logger of the records at level and above, level is debug, info (by default), warn or error.
+// The stack traces of the errors are written at debug level.
+func NewLogger(logWriter io.Writer, level string) (*Logger, error) {
+	var lvl zerolog.Level
+	switch level {
+	case LevelDebug:
+		lvl = zerolog.DebugLevel
+	case LevelInfo, "":
+		lvl = zerolog.InfoLevel
+	case LevelWarn:
+		lvl = zerolog.WarnLevel
+	case LevelError:
+		lvl = zerolog.ErrorLevel
+	default:
+		return nil, errors.Errorf("unknown log level %v", level)
+	}
+	logger := zerolog.New(logWriter).Level(lvl).With().Timestamp().Logger()
+	return &Logger{logger: &logger, stacks: lvl == zerolog.DebugLevel}, nil
 }
 
-func NewLogger(logWriter io.Writer, isDebug bool) *Logger {
-	zerolog.SetGlobalLevel(zerolog.InfoLevel)
-	logger := zerolog.New(logWriter).With().Timestamp().Logger()
-	return &Logger{logger: &logger, isDebug: isDebug}
+func (l *Logger) Debug(ctx context.Context, msg string, fields ...usecase.Field) {
+	l.write(ctx, l.logger.Debug(), msg, fields)
 }
 
-func (l *Logger) log(ctx context.Context, message string, args ...interface{}) {
-	l.logger.Log().Str("Request id", util.GetRequestID(ctx)).Msgf(message, args...)
+func (l *Logger) Info(ctx context.Context, msg string, fields ...usecase.Field) {
+	l.write(ctx, l.logger.Info(), msg, fields)
 }
 
-func (l *Logger) Log(ctx context.Context, message interface{}, args ...interface{}) {
-	switch mess := message.(type) {
-	case error:
-		if l.isDebug {
-			err, ok := errors.Cause(mess).(stackTracer)
-			if ok {
-				st := err.StackTrace()
-				l.log(ctx, fmt.Sprintf("%+v", st), args...)
-				return
+func (l *Logger) Warn(ctx context.Context, msg string, fields ...usecase.Field) {
+	l.write(ctx, l.logger.Warn(), msg, fields)
+}
+
+func (l *Logger) Error(ctx context.Context, msg string, fields ...usecase.Field) {
+	l.write(ctx, l.logger.Error(), msg, fields)
+}
+
+// write writes the record if its level is enabled.
+func (l *Logger) write(ctx context.Context, e *zerolog.Event, msg string, fields []usecase.Field) {
+	if e == nil {
+		return
+	}
+	if id := util.GetRequestID(ctx); id != "" {
+		e.Str(RequestIDKey, id)
+	}
+	for _, f := range fields {
+		switch v := f.Value.(type) {
+		case string:
+			e.Str(f.Key, v)
+		case int:
+			e.Int(f.Key, v)
+		case int64:
+			e.Int64(f.Key, v)
+		case bool:
+			e.Bool(f.Key, v)
+		case time.Duration:
+			e.Dur(f.Key, v)
+		case time.Time:
+			e.Time(f.Key, v)
+		case error:
+			e.AnErr(f.Key, v)
+			if st, ok := errors.Cause(v).(stackTracer); ok && l.stacks {
+				e.Str(f.Key+"_stack", fmt.Sprintf("%+v", st.StackTrace()))
 			}
+		default:
+			e.Interface(f.Key, v)
 		}
-		l.log(ctx, mess.Error(), args...)
-	case string:
-		l.log(ctx, mess, args...)
-	default:
-		l.log(ctx, fmt.Sprintf("debug message %v has unknown type %v", message, mess), args...)
 	}
+	e.Msg(msg)
 }
 
 type stackTracer interface {
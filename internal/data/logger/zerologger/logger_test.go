@@ -0,0 +1,76 @@
+package zerologger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redselig/currencier/internal/domain/usecase"
+	"github.com/redselig/currencier/internal/util"
+)
+
+func TestLogger_Level(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger, err := NewLogger(buf, LevelWarn)
+	require.Nil(t, err)
+	ctx := context.Background()
+
+	logger.Debug(ctx, "debug")
+	logger.Info(ctx, "info")
+	logger.Warn(ctx, "warn")
+	logger.Error(ctx, "error")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	require.Equal(t, "warn", record(t, lines[0])["level"])
+	require.Equal(t, "error", record(t, lines[1])["level"])
+
+	_, err = NewLogger(buf, "verbose")
+	require.NotNil(t, err)
+}
+
+func TestLogger_Fields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger, err := NewLogger(buf, "")
+	require.Nil(t, err)
+	ctx := util.WithRequestID(context.Background(), "req-1")
+
+	logger.Info(ctx, "request",
+		usecase.String("method", "GET"),
+		usecase.Int("status", 200),
+		usecase.Int64("bytes", 42),
+		usecase.Bool("cached", true),
+		usecase.Duration("latency", 1500*time.Microsecond),
+		usecase.Err(errors.New("boom")))
+	rec := record(t, buf.String())
+	require.Equal(t, "info", rec["level"])
+	require.Equal(t, "request", rec["message"])
+	require.Equal(t, "req-1", rec[RequestIDKey])
+	require.Equal(t, "GET", rec["method"])
+	require.Equal(t, 200.0, rec["status"])
+	require.Equal(t, 42.0, rec["bytes"])
+	require.Equal(t, true, rec["cached"])
+	require.Equal(t, 1.5, rec["latency"])
+	require.Equal(t, "boom", rec["error"])
+	require.NotContains(t, rec, "error_stack")
+
+	buf.Reset()
+	logger, err = NewLogger(buf, LevelDebug)
+	require.Nil(t, err)
+	logger.Error(context.Background(), "failed", usecase.Err(errors.Wrap(errors.New("boom"), "can't load")))
+	rec = record(t, buf.String())
+	require.Equal(t, "can't load: boom", rec["error"])
+	require.Contains(t, rec["error_stack"], "TestLogger_Fields")
+	require.NotContains(t, rec, RequestIDKey)
+}
+
+func record(t *testing.T, line string) map[string]interface{} {
+	rec := map[string]interface{}{}
+	require.Nil(t, json.Unmarshal([]byte(line), &rec))
+	return rec
+}
@@ -0,0 +1,205 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/redselig/currencier/internal/domain/entity"
+)
+
+const (
+	namespace = "currencier"
+
+	FetchSuccess = "success"
+	FetchFailure = "failure"
+
+	// ageTimeout limits the query of the newest rate date on a scrape.
+	ageTimeout = 5 * time.Second
+)
+
+// Metrics are the prometheus metrics of the service, every instance has its own registry.
+type Metrics struct {
+	registry  *prometheus.Registry
+	requests  *prometheus.CounterVec
+	latency   *prometheus.HistogramVec
+	fetches   *prometheus.CounterVec
+	fetchTime *prometheus.HistogramVec
+	written   prometheus.Counter
+}
+
+// NewMetrics creates registry with the service metrics and the go runtime and process ones.
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Number of handled http requests by route, method and status.",
+		}, []string{"route", "method", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of handled http requests by route, method and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		fetches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "provider",
+			Name:      "fetches_total",
+			Help:      "Number of rates fetches from the upstream providers by result, every retry is counted.",
+		}, []string{"provider", "result"}),
+		fetchTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "provider",
+			Name:      "fetch_duration_seconds",
+			Help:      "Duration of rates fetches from the upstream providers.",
+			Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 30},
+		}, []string{"provider"}),
+		written: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "rates",
+			Name:      "written_total",
+			Help:      "Number of rates rows written to the repository.",
+		}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.latency, m.fetches, m.fetchTime, m.written,
+	)
+	return m
+}
+
+// Handler serves the metrics in the prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest counts the handled request, route is the path template rather than the path to keep cardinality low.
+func (m *Metrics) ObserveRequest(route, method string, status int, latency time.Duration) {
+	code := strconv.Itoa(status)
+	m.requests.WithLabelValues(route, method, code).Inc()
+	m.latency.WithLabelValues(route, method, code).Observe(latency.Seconds())
+}
+
+// RegisterDB exposes the stats of the db connection pool, name is the db_name label.
+func (m *Metrics) RegisterDB(name string, db *sql.DB) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterRatesAge exposes the age of the newest rate stored in the repository, it's read on every scrape.
+// The age is counted from the start of the day the rate is set on, it's +Inf if there are no rates
+// and NaN if the repository fails.
+func (m *Metrics) RegisterRatesAge(repo entity.CurrencyInternalRepository) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "rates",
+		Name:      "newest_age_seconds",
+		Help:      "Age of the newest stored rate, counted from the start of the day it is set on.",
+	}, func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), ageTimeout)
+		defer cancel()
+		newest, err := repo.GetNewestDate(ctx)
+		if err != nil {
+			return math.NaN()
+		}
+		if newest.IsZero() {
+			return math.Inf(1)
+		}
+		return time.Since(newest).Seconds()
+	}))
+}
+
+// RegisterBreaker exposes the circuit breaker state of the provider read on every scrape,
+// the gauge of the current one of states is 1 and the others are 0.
+func (m *Metrics) RegisterBreaker(provider string, state func() string, states ...string) error {
+	for _, st := range states {
+		st := st
+		gauge := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "provider",
+			Name:        "breaker_state",
+			Help:        "State of the circuit breaker of the upstream provider.",
+			ConstLabels: prometheus.Labels{"provider": provider, "state": st},
+		}, func() float64 {
+			if state() == st {
+				return 1
+			}
+			return 0
+		})
+		if err := m.registry.Register(gauge); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Provider counts fetches of the provider and their duration.
+func (m *Metrics) Provider(name string, provider entity.CurrencyExternalRepository) entity.CurrencyExternalRepository {
+	return &instrumentedProvider{
+		provider: provider,
+		fetches:  m.fetches.MustCurryWith(prometheus.Labels{"provider": name}),
+		duration: m.fetchTime.WithLabelValues(name),
+	}
+}
+
+// Repository counts the rows written by SetAll of the repository.
+func (m *Metrics) Repository(repo entity.CurrencyInternalRepository) entity.CurrencyInternalRepository {
+	return &instrumentedRepo{
+		CurrencyInternalRepository: repo,
+		written:                    m.written,
+	}
+}
+
+type instrumentedProvider struct {
+	provider entity.CurrencyExternalRepository
+	fetches  *prometheus.CounterVec
+	duration prometheus.Observer
+}
+
+func (p *instrumentedProvider) Load(ctx context.Context) ([]*entity.Currency, error) {
+	return p.observe(func() ([]*entity.Currency, error) {
+		return p.provider.Load(ctx)
+	})
+}
+
+func (p *instrumentedProvider) LoadOnDate(ctx context.Context, date time.Time) ([]*entity.Currency, error) {
+	return p.observe(func() ([]*entity.Currency, error) {
+		return p.provider.LoadOnDate(ctx, date)
+	})
+}
+
+func (p *instrumentedProvider) observe(load func() ([]*entity.Currency, error)) ([]*entity.Currency, error) {
+	start := time.Now()
+	cs, err := load()
+	p.duration.Observe(time.Since(start).Seconds())
+	result := FetchSuccess
+	if err != nil {
+		result = FetchFailure
+	}
+	p.fetches.WithLabelValues(result).Inc()
+	return cs, err
+}
+
+type instrumentedRepo struct {
+	entity.CurrencyInternalRepository
+	written prometheus.Counter
+}
+
+func (repo *instrumentedRepo) SetAll(ctx context.Context, cs []*entity.Currency) error {
+	if err := repo.CurrencyInternalRepository.SetAll(ctx, cs); err != nil {
+		return err
+	}
+	repo.written.Add(float64(len(cs)))
+	return nil
+}
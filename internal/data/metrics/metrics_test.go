@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redselig/currencier/internal/data/repository/memory"
+	"github.com/redselig/currencier/internal/domain/entity"
+)
+
+type testProvider struct {
+	err error
+}
+
+func (p *testProvider) Load(ctx context.Context) ([]*entity.Currency, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	return []*entity.Currency{{ID: "R01235", CharCode: "USD"}}, nil
+}
+
+func (p *testProvider) LoadOnDate(ctx context.Context, date time.Time) ([]*entity.Currency, error) {
+	return p.Load(ctx)
+}
+
+func TestMetrics_Provider(t *testing.T) {
+	m := NewMetrics()
+	ctx := context.Background()
+	up := &testProvider{}
+	provider := m.Provider("cbr", up)
+
+	_, err := provider.Load(ctx)
+	require.Nil(t, err)
+	_, err = provider.LoadOnDate(ctx, time.Now())
+	require.Nil(t, err)
+	up.err = errors.New("down")
+	_, err = provider.Load(ctx)
+	require.Equal(t, up.err, err)
+
+	require.Equal(t, 2.0, testutil.ToFloat64(m.fetches.WithLabelValues("cbr", FetchSuccess)))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.fetches.WithLabelValues("cbr", FetchFailure)))
+	require.Equal(t, 1, testutil.CollectAndCount(m.fetchTime))
+}
+
+func TestMetrics_RegisterBreaker(t *testing.T) {
+	m := NewMetrics()
+	state := "closed"
+	require.Nil(t, m.RegisterBreaker("cbr", func() string { return state }, "closed", "open"))
+	require.NotNil(t, m.RegisterBreaker("cbr", func() string { return state }, "closed", "open"))
+
+	breaker := func() map[string]float64 {
+		mfs, err := m.registry.Gather()
+		require.Nil(t, err)
+		states := map[string]float64{}
+		for _, mf := range mfs {
+			if mf.GetName() != "currencier_provider_breaker_state" {
+				continue
+			}
+			for _, metric := range mf.GetMetric() {
+				for _, l := range metric.GetLabel() {
+					if l.GetName() == "state" {
+						states[l.GetValue()] = metric.GetGauge().GetValue()
+					}
+				}
+			}
+		}
+		return states
+	}
+	require.Equal(t, map[string]float64{"closed": 1, "open": 0}, breaker())
+	state = "open"
+	require.Equal(t, map[string]float64{"closed": 0, "open": 1}, breaker())
+}
+
+func TestMetrics_Repository(t *testing.T) {
+	m := NewMetrics()
+	ctx := context.Background()
+	mem := memory.NewMemRepo()
+	m.RegisterRatesAge(mem)
+	age := func() float64 {
+		mfs, err := m.registry.Gather()
+		require.Nil(t, err)
+		for _, mf := range mfs {
+			if mf.GetName() == "currencier_rates_newest_age_seconds" {
+				return mf.GetMetric()[0].GetGauge().GetValue()
+			}
+		}
+		t.Fatal("no rates age metric")
+		return 0
+	}
+	require.True(t, math.IsInf(age(), 1))
+
+	yesterday := time.Now().UTC().AddDate(0, 0, -1)
+	repo := m.Repository(mem)
+	require.Nil(t, repo.SetAll(ctx, []*entity.Currency{
+		{ID: "R01235", CharCode: "USD", Date: yesterday.AddDate(0, 0, -1)},
+		{ID: "R01239", CharCode: "EUR", Date: yesterday},
+	}))
+	require.Equal(t, 2.0, testutil.ToFloat64(m.written))
+
+	day := time.Date(yesterday.Year(), yesterday.Month(), yesterday.Day(), 0, 0, 0, 0, time.UTC)
+	require.InDelta(t, time.Since(day).Seconds(), age(), 5)
+}
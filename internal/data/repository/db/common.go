@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/redselig/currencier/internal/data/tracing"
+	"github.com/redselig/currencier/internal/domain/entity"
+)
+
+const tracerName = "github.com/redselig/currencier/internal/data/repository/db"
+
+// startQuery starts span of the repository operation op, the span is ended by tracing.End.
+func startQuery(ctx context.Context, system attribute.KeyValue, op string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, tracerName, op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(system, semconv.DBOperationKey.String(op)))
+}
+
+func rowToCurrency(row *sql.Row, errorString string) (*entity.Currency, error) {
+	if row == nil {
+		return nil, entity.ErrNotFound
+	}
+	c := entity.Currency{}
+	err := row.Scan(&c.ID, &c.NumCode, &c.CharCode, &c.Nominal, &c.Name, &c.Value, &c.Base, &c.Provider, &c.Date)
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrNotFound
+	}
+	if err != nil {
+		return nil, SQLError(err, errorString)
+	}
+	return &c, nil
+}
+
+// rowToDate returns the date of the row, zero time if there is no row.
+func rowToDate(row *sql.Row, errorString string) (time.Time, error) {
+	var date time.Time
+	err := row.Scan(&date)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, SQLError(err, errorString)
+	}
+	return date, nil
+}
+
+func rowsToCurrencies(rows *sql.Rows, errorString string) ([]*entity.Currency, error) {
+	var currencies []*entity.Currency
+	for rows.Next() {
+		c := entity.Currency{}
+		err := rows.Scan(&c.ID, &c.NumCode, &c.CharCode, &c.Nominal, &c.Name, &c.Value, &c.Base, &c.Provider, &c.Date)
+		if err != nil {
+			return nil, SQLError(err, errorString)
+		}
+		currencies = append(currencies, &c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, SQLError(err, errorString)
+	}
+	return currencies, nil
+}
+
+// ping checks the db is reachable, the error doesn't contain the dsn not to leak the password.
+func ping(ctx context.Context, db *sql.DB) error {
+	if err := db.PingContext(ctx); err != nil {
+		return errors.Wrap(err, "can't ping db")
+	}
+	return nil
+}
+
+// nominal returns the number of units the rate is set for, sources omit it for single units.
+func nominal(c *entity.Currency) int {
+	if c.Nominal < 1 {
+		return 1
+	}
+	return c.Nominal
+}
+
+func SQLError(err error, message string) error {
+	switch err {
+	case sql.ErrNoRows:
+		return nil
+	default:
+		return errors.Wrap(err, message)
+	}
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/pkg/errors"
+)
+
+const ErrLock = "can't take lock %v"
+
+// AdvisoryLock is a session level lock held by a dedicated connection,
+// the db releases it by itself if the holder dies and its session ends.
+type AdvisoryLock struct {
+	conn   *sql.Conn
+	name   string
+	unlock string
+}
+
+// TryLock takes pg advisory lock named name without waiting, nil is returned if it's held by another session.
+func (repo *PGSRepo) TryLock(ctx context.Context, name string) (*AdvisoryLock, error) {
+	return tryLock(ctx, repo.db, name, "select pg_try_advisory_lock(hashtext($1));",
+		"select pg_advisory_unlock(hashtext($1));")
+}
+
+// TryLock takes mysql named lock without waiting, nil is returned if it's held by another session.
+func (repo *MySQLRepo) TryLock(ctx context.Context, name string) (*AdvisoryLock, error) {
+	return tryLock(ctx, repo.db, name, "select coalesce(get_lock(?, 0), 0)=1;", "select release_lock(?);")
+}
+
+func tryLock(ctx context.Context, db *sql.DB, name, lock, unlock string) (*AdvisoryLock, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, ErrLock, name)
+	}
+	var locked bool
+	if err := conn.QueryRowContext(ctx, lock, name).Scan(&locked); err != nil {
+		conn.Close()
+		return nil, errors.Wrapf(err, ErrLock, name)
+	}
+	if !locked {
+		return nil, conn.Close()
+	}
+	return &AdvisoryLock{
+		conn:   conn,
+		name:   name,
+		unlock: unlock,
+	}, nil
+}
+
+// Check returns error if the session holding the lock is lost.
+func (l *AdvisoryLock) Check(ctx context.Context) error {
+	if err := l.conn.PingContext(ctx); err != nil {
+		return errors.Wrapf(err, "lock %v is lost", l.name)
+	}
+	return nil
+}
+
+// Unlock releases the lock and its connection.
+func (l *AdvisoryLock) Unlock(ctx context.Context) error {
+	defer l.conn.Close()
+	if _, err := l.conn.ExecContext(ctx, l.unlock, l.name); err != nil {
+		// the session must not go back to the pool holding the lock
+		l.conn.Raw(func(interface{}) error { return driver.ErrBadConn }) //nolint:errcheck
+		return errors.Wrapf(err, "can't release lock %v", l.name)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPGSRepo_TryLock(t *testing.T) {
+	ctx := context.TODO()
+	db, mock, err := sqlmock.New()
+	require.Nil(t, err)
+	defer db.Close()
+	repo := &PGSRepo{db}
+	const name = "currencier-updater"
+
+	t.Run("lock is taken", func(t *testing.T) {
+		mock.ExpectQuery(`select pg_try_advisory_lock\(hashtext\(\$1\)\)`).
+			WithArgs(name).
+			WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(true))
+		mock.ExpectExec(`select pg_advisory_unlock\(hashtext\(\$1\)\)`).
+			WithArgs(name).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		lock, err := repo.TryLock(ctx, name)
+		require.Nil(t, err)
+		require.NotNil(t, lock)
+		require.Nil(t, lock.Check(ctx))
+		require.Nil(t, lock.Unlock(ctx))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("lock is held by another session", func(t *testing.T) {
+		mock.ExpectQuery(`select pg_try_advisory_lock`).
+			WithArgs(name).
+			WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(false))
+
+		lock, err := repo.TryLock(ctx, name)
+		require.Nil(t, err)
+		require.Nil(t, lock)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"database/sql"
+	"embed"
+
+	"github.com/pkg/errors"
+	"github.com/pressly/goose/v3"
+)
+
+const (
+	MigrateUp     = "up"
+	MigrateDown   = "down"
+	MigrateStatus = "status"
+
+	ErrMigrate = "can't migrate db"
+)
+
+// migrations keeps schema migrations for every sql dialect in the goose format.
+//
+//go:embed migrations
+var migrations embed.FS
+
+// migrate runs the goose command with the embedded migrations from dir.
+func migrate(db *sql.DB, dialect, dir, command string) error {
+	switch command {
+	case MigrateUp, MigrateDown, MigrateStatus:
+	default:
+		return errors.Errorf("%v: unknown command %v", ErrMigrate, command)
+	}
+	goose.SetBaseFS(migrations)
+	if err := goose.SetDialect(dialect); err != nil {
+		return errors.Wrap(err, ErrMigrate)
+	}
+	if err := goose.Run(command, db, dir); err != nil {
+		return errors.Wrap(err, ErrMigrate)
+	}
+	return nil
+}
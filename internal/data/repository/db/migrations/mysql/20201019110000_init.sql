@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS currency
+(
+    id        VARCHAR(16)    NOT NULL,
+    num_code  INT            NOT NULL DEFAULT 0,
+    char_code VARCHAR(3)     NOT NULL DEFAULT '',
+    nominal   INT            NOT NULL DEFAULT 1,
+    name      VARCHAR(255)   NOT NULL,
+    rate      DECIMAL(20, 8) NOT NULL,
+    insert_dt DATETIME       NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    KEY currency_char_code_idx (char_code),
+    KEY currency_num_code_idx (num_code)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS currency_rate
+(
+    id        VARCHAR(16)    NOT NULL,
+    rate_date DATE           NOT NULL,
+    num_code  INT            NOT NULL DEFAULT 0,
+    char_code VARCHAR(3)     NOT NULL DEFAULT '',
+    nominal   INT            NOT NULL DEFAULT 1,
+    name      VARCHAR(255)   NOT NULL,
+    rate      DECIMAL(20, 8) NOT NULL,
+    insert_dt DATETIME       NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id, rate_date)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE currency_rate;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE currency;
+-- +goose StatementEnd
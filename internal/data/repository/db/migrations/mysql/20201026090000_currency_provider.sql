@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE currency
+    ADD COLUMN provider VARCHAR(64) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE currency_rate
+    ADD COLUMN provider VARCHAR(64) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE currency
+    DROP COLUMN provider;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE currency_rate
+    DROP COLUMN provider;
+-- +goose StatementEnd
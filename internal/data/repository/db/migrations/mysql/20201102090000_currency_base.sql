@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE currency
+    ADD COLUMN base CHAR(3) NOT NULL DEFAULT 'RUB';
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE currency_rate
+    ADD COLUMN base CHAR(3) NOT NULL DEFAULT 'RUB';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE currency
+    DROP COLUMN base;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE currency_rate
+    DROP COLUMN base;
+-- +goose StatementEnd
@@ -10,9 +10,6 @@ CREATE TABLE IF NOT EXISTS public.currency
     CONSTRAINT currency_pkey PRIMARY KEY (id)
 )
     TABLESPACE pg_default;
-
-ALTER TABLE public.currency
-    OWNER to igor;
 -- +goose StatementEnd
 
 -- +goose Down
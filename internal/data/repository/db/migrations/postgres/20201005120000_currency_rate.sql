@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.currency_rate
+(
+    id character varying COLLATE pg_catalog."default" NOT NULL,
+    rate_date date NOT NULL,
+    name character varying COLLATE pg_catalog."default" NOT NULL,
+    rate numeric NOT NULL,
+    insert_dt timestamp with time zone NOT NULL DEFAULT timezone('utc'::text, now()),
+    CONSTRAINT currency_rate_pkey PRIMARY KEY (id, rate_date)
+)
+    TABLESPACE pg_default;
+
+INSERT INTO public.currency_rate (id, rate_date, name, rate, insert_dt)
+SELECT id, insert_dt::date, name, rate, insert_dt
+FROM public.currency
+ON CONFLICT (id, rate_date) DO NOTHING;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE public.currency_rate;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.currency
+    ADD COLUMN IF NOT EXISTS num_code integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS char_code character varying COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS nominal integer NOT NULL DEFAULT 1;
+CREATE INDEX IF NOT EXISTS currency_char_code_idx ON public.currency (char_code);
+CREATE INDEX IF NOT EXISTS currency_num_code_idx ON public.currency (num_code);
+
+ALTER TABLE public.currency_rate
+    ADD COLUMN IF NOT EXISTS num_code integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS char_code character varying COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS nominal integer NOT NULL DEFAULT 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.currency_num_code_idx;
+DROP INDEX IF EXISTS public.currency_char_code_idx;
+ALTER TABLE public.currency
+    DROP COLUMN num_code,
+    DROP COLUMN char_code,
+    DROP COLUMN nominal;
+ALTER TABLE public.currency_rate
+    DROP COLUMN num_code,
+    DROP COLUMN char_code,
+    DROP COLUMN nominal;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.currency
+    ADD COLUMN IF NOT EXISTS provider character varying COLLATE pg_catalog."default" NOT NULL DEFAULT '';
+ALTER TABLE public.currency_rate
+    ADD COLUMN IF NOT EXISTS provider character varying COLLATE pg_catalog."default" NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.currency
+    DROP COLUMN provider;
+ALTER TABLE public.currency_rate
+    DROP COLUMN provider;
+-- +goose StatementEnd
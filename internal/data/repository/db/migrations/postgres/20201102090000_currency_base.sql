@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.currency
+    ADD COLUMN IF NOT EXISTS base character varying(3) COLLATE pg_catalog."default" NOT NULL DEFAULT 'RUB';
+ALTER TABLE public.currency_rate
+    ADD COLUMN IF NOT EXISTS base character varying(3) COLLATE pg_catalog."default" NOT NULL DEFAULT 'RUB';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.currency
+    DROP COLUMN base;
+ALTER TABLE public.currency_rate
+    DROP COLUMN base;
+-- +goose StatementEnd
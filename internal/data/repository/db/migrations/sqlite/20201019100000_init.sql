@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS currency
+(
+    id        TEXT      NOT NULL PRIMARY KEY,
+    num_code  INTEGER   NOT NULL DEFAULT 0,
+    char_code TEXT      NOT NULL DEFAULT '',
+    nominal   INTEGER   NOT NULL DEFAULT 1,
+    name      TEXT      NOT NULL,
+    rate      TEXT      NOT NULL,
+    insert_dt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS currency_char_code_idx ON currency (char_code);
+CREATE INDEX IF NOT EXISTS currency_num_code_idx ON currency (num_code);
+
+CREATE TABLE IF NOT EXISTS currency_rate
+(
+    id        TEXT      NOT NULL,
+    rate_date DATE      NOT NULL,
+    num_code  INTEGER   NOT NULL DEFAULT 0,
+    char_code TEXT      NOT NULL DEFAULT '',
+    nominal   INTEGER   NOT NULL DEFAULT 1,
+    name      TEXT      NOT NULL,
+    rate      TEXT      NOT NULL,
+    insert_dt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id, rate_date)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE currency_rate;
+DROP TABLE currency;
+-- +goose StatementEnd
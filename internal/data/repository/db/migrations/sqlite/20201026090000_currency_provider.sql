@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE currency
+    ADD COLUMN provider TEXT NOT NULL DEFAULT '';
+ALTER TABLE currency_rate
+    ADD COLUMN provider TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE currency
+    DROP COLUMN provider;
+ALTER TABLE currency_rate
+    DROP COLUMN provider;
+-- +goose StatementEnd
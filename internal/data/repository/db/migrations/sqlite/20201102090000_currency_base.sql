@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE currency
+    ADD COLUMN base TEXT NOT NULL DEFAULT 'RUB';
+ALTER TABLE currency_rate
+    ADD COLUMN base TEXT NOT NULL DEFAULT 'RUB';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE currency
+    DROP COLUMN base;
+ALTER TABLE currency_rate
+    DROP COLUMN base;
+-- +goose StatementEnd
@@ -0,0 +1,197 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+
+	"github.com/redselig/currencier/internal/data/tracing"
+	"github.com/redselig/currencier/internal/domain/entity"
+)
+
+// MySQLDialect is the db dialect and the name of the mysql driver.
+const MySQLDialect = "mysql"
+
+var _ entity.CurrencyInternalRepository = (*MySQLRepo)(nil)
+
+type MySQLRepo struct {
+	db *sql.DB
+}
+
+// NewMySQLRepo connects to mysql, dates are always parsed to time.Time in UTC whatever the dsn says.
+func NewMySQLRepo(dsn string) (*MySQLRepo, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't parse dsn %v", dsn)
+	}
+	cfg.ParseTime = true
+	cfg.Loc = time.UTC
+	db, err := sql.Open(MySQLDialect, cfg.FormatDSN())
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't create connect to db with dsn %v by driver %v", dsn, MySQLDialect)
+	}
+	return &MySQLRepo{
+		db: db,
+	}, nil
+}
+
+func (repo *MySQLRepo) SetAll(ctx context.Context, cs []*entity.Currency) (err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemMySQL, "SetAll")
+	defer func() { tracing.End(span, err) }()
+
+	if len(cs) == 0 {
+		return nil
+	}
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrapf(err, ErrAdd)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback() //nolint:errcheck
+		}
+	}()
+
+	placeholders := strings.TrimSuffix(strings.Repeat("(?,?,?,?,?,?,?,?,?),", len(cs)), ",")
+	var vals, histVals []interface{}
+	for _, row := range cs {
+		date := row.RateDate()
+		vals = append(vals, row.ID, row.NumCode, row.CharCode, nominal(row), row.Name, row.Value, row.Base, row.Provider, date)
+		histVals = append(histVals, row.ID, date, row.NumCode, row.CharCode, nominal(row), row.Name, row.Value, row.Base, row.Provider)
+	}
+	// older rates (e.g. from backfill) go to history only and don't replace the latest ones,
+	// mysql assigns columns left to right, so insert_dt must be the last one
+	sqlStr := `insert into currency (id, num_code, char_code, nominal, name, rate, base, provider, insert_dt) values ` + placeholders + `
+				on duplicate key update
+				num_code=if(insert_dt<=values(insert_dt), values(num_code), num_code),
+				char_code=if(insert_dt<=values(insert_dt), values(char_code), char_code),
+				nominal=if(insert_dt<=values(insert_dt), values(nominal), nominal),
+				name=if(insert_dt<=values(insert_dt), values(name), name),
+				rate=if(insert_dt<=values(insert_dt), values(rate), rate),
+				base=if(insert_dt<=values(insert_dt), values(base), base),
+				provider=if(insert_dt<=values(insert_dt), values(provider), provider),
+				insert_dt=greatest(insert_dt, values(insert_dt));`
+	histStr := `insert into currency_rate (id, rate_date, num_code, char_code, nominal, name, rate, base, provider) values ` + placeholders + `
+				on duplicate key update num_code=values(num_code), char_code=values(char_code),
+				nominal=values(nominal), name=values(name), rate=values(rate), base=values(base), provider=values(provider),
+				insert_dt=now();`
+
+	if _, err = tx.ExecContext(ctx, sqlStr, vals...); err != nil {
+		return errors.Wrapf(err, ErrAdd)
+	}
+	if _, err = tx.ExecContext(ctx, histStr, histVals...); err != nil {
+		return errors.Wrapf(err, ErrAdd)
+	}
+	if err = tx.Commit(); err != nil {
+		return errors.Wrapf(err, ErrAdd)
+	}
+	return nil
+}
+
+func (repo *MySQLRepo) GetByID(ctx context.Context, id string) (c *entity.Currency, err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemMySQL, "GetByID")
+	defer func() { tracing.End(span, err) }()
+
+	row := repo.db.QueryRowContext(ctx, `select id, num_code, char_code, nominal, name, rate, base, provider, insert_dt
+												from currency where id=?;`, id)
+	return rowToCurrency(row, ErrGet)
+}
+
+func (repo *MySQLRepo) GetByCharCode(ctx context.Context, code string) (c *entity.Currency, err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemMySQL, "GetByCharCode")
+	defer func() { tracing.End(span, err) }()
+
+	row := repo.db.QueryRowContext(ctx, `select id, num_code, char_code, nominal, name, rate, base, provider, insert_dt
+												from currency where char_code=? order by insert_dt desc, id limit 1;`, code)
+	return rowToCurrency(row, ErrGet)
+}
+
+func (repo *MySQLRepo) GetByNumCode(ctx context.Context, code int) (c *entity.Currency, err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemMySQL, "GetByNumCode")
+	defer func() { tracing.End(span, err) }()
+
+	row := repo.db.QueryRowContext(ctx, `select id, num_code, char_code, nominal, name, rate, base, provider, insert_dt
+												from currency where num_code=? order by insert_dt desc, id limit 1;`, code)
+	return rowToCurrency(row, ErrGet)
+}
+
+func (repo *MySQLRepo) GetNewestDate(ctx context.Context) (date time.Time, err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemMySQL, "GetNewestDate")
+	defer func() { tracing.End(span, err) }()
+
+	row := repo.db.QueryRowContext(ctx, `select insert_dt from currency order by insert_dt desc limit 1;`)
+	return rowToDate(row, ErrGet)
+}
+
+func (repo *MySQLRepo) GetByIDOnDate(ctx context.Context, id string, date time.Time) (c *entity.Currency, err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemMySQL, "GetByIDOnDate")
+	defer func() { tracing.End(span, err) }()
+
+	row := repo.db.QueryRowContext(ctx, `select id, num_code, char_code, nominal, name, rate, base, provider, rate_date
+												from currency_rate where id=? and rate_date<=?
+												order by rate_date desc limit 1;`, id, entity.Day(date))
+	return rowToCurrency(row, ErrGet)
+}
+
+func (repo *MySQLRepo) GetHistory(ctx context.Context, id string, from, to time.Time) (cs []*entity.Currency, err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemMySQL, "GetHistory")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := repo.db.QueryContext(ctx, `select id, num_code, char_code, nominal, name, rate, base, provider, rate_date
+												from currency_rate where id=? and rate_date between ? and ?
+												order by rate_date;`, id, entity.Day(from), entity.Day(to))
+	if err != nil && err != sql.ErrNoRows {
+		return nil, SQLError(err, ErrGet)
+	}
+	defer rows.Close()
+	return rowsToCurrencies(rows, ErrGet)
+}
+
+func (repo *MySQLRepo) GetPage(ctx context.Context, limit, offset int) (cs []*entity.Currency, err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemMySQL, "GetPage")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := repo.db.QueryContext(ctx, `select id, num_code, char_code, nominal, name, rate, base, provider, insert_dt
+												from currency order by id limit ? offset ?;`, limit, offset)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, SQLError(err, ErrGet)
+	}
+	defer rows.Close()
+	return rowsToCurrencies(rows, ErrGet)
+}
+
+func (repo *MySQLRepo) GetLazy(ctx context.Context, limit int, lastID string) (cs []*entity.Currency, err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemMySQL, "GetLazy")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := repo.db.QueryContext(ctx, `select id, num_code, char_code, nominal, name, rate, base, provider, insert_dt
+												from currency where id>? order by id limit ?;`, lastID, limit)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, SQLError(err, ErrGet)
+	}
+	defer rows.Close()
+	return rowsToCurrencies(rows, ErrGet)
+}
+
+func (repo *MySQLRepo) Ping(ctx context.Context) error {
+	return ping(ctx, repo.db)
+}
+
+// Migrate runs up, down or status command of the embedded schema migrations.
+func (repo *MySQLRepo) Migrate(command string) error {
+	return migrate(repo.db, "mysql", "migrations/mysql", command)
+}
+
+func (repo *MySQLRepo) Close() error {
+	return repo.db.Close()
+}
+
+// DB returns the connection pool, e.g. to collect its stats.
+func (repo *MySQLRepo) DB() *sql.DB {
+	return repo.db
+}
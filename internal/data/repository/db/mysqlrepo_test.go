@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redselig/currencier/internal/domain/entity"
+)
+
+func TestMySQLRepo(t *testing.T) {
+	ctx := context.TODO()
+	db, mock, err := sqlmock.New()
+	require.Nil(t, err)
+	defer db.Close()
+	repo := &MySQLRepo{db}
+
+	t.Run("good test: get currency by id", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "num_code", "char_code", "nominal", "name", "rate", "base", "provider", "insert_dt"}).
+			AddRow(testID, testNumCode, testCharCode, 1, testName, testRate, testBase, testProvider, testDate)
+		mock.ExpectQuery(`select (.+) from currency where id=\?`).
+			WithArgs(testID).
+			WillReturnRows(rows)
+
+		c, err := repo.GetByID(ctx, testID)
+		require.Nil(t, err)
+		require.Equal(t, &testCurrency, c)
+	})
+	t.Run("good test: get currency on date", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "num_code", "char_code", "nominal", "name", "rate", "base", "provider", "rate_date"}).
+			AddRow(testID, testNumCode, testCharCode, 1, testName, testRate, testBase, testProvider, testDate)
+		mock.ExpectQuery(`select (.+) from currency_rate where id=\? and rate_date<=\?`).
+			WithArgs(testID, testDate).
+			WillReturnRows(rows)
+
+		c, err := repo.GetByIDOnDate(ctx, testID, testDate)
+		require.Nil(t, err)
+		require.Equal(t, &testCurrency, c)
+	})
+	t.Run("good test: save 1 currency to db", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`insert into currency (.+) on duplicate key update`).
+			WithArgs(testID, testNumCode, testCharCode, 1, testName, testRate, testBase, testProvider, testDate).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`insert into currency_rate (.+) on duplicate key update`).
+			WithArgs(testID, testDate, testNumCode, testCharCode, 1, testName, testRate, testBase, testProvider).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := repo.SetAll(ctx, []*entity.Currency{&testCurrency})
+		require.Nil(t, err)
+	})
+	t.Run("return error: save currency to db", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`insert into currency`).
+			WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		err := repo.SetAll(ctx, []*entity.Currency{&testCurrency})
+		require.Truef(t, errors.Is(err, sql.ErrConnDone), "SetAll not return cause error")
+	})
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -4,9 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
 
+	"github.com/redselig/currencier/internal/data/tracing"
 	"github.com/redselig/currencier/internal/domain/entity"
 )
 
@@ -31,70 +35,139 @@ func NewPGSRepo(driver, dsn string) (*PGSRepo, error) {
 	}, nil
 }
 
-func (repo *PGSRepo) SetAll(ctx context.Context, cs []*entity.Currency) error {
-	sqlStr := "insert into public.currency (id, name, rate) values "
-	var vals []interface{}
-	for i, row := range cs {
-		sqlStr += fmt.Sprintf("($%v,$%v,$%v),", (i*3)+1, (i*3)+2, (i*3)+3)
-		vals = append(vals, row.ID, row.Name, row.Value/float64(row.Nominal))
+func (repo *PGSRepo) SetAll(ctx context.Context, cs []*entity.Currency) (err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemPostgreSQL, "SetAll")
+	defer func() { tracing.End(span, err) }()
+
+	if len(cs) == 0 {
+		return nil
 	}
-	sqlStr = sqlStr[0 : len(sqlStr)-1]
-	sqlStr += " on conflict (id) do UPDATE SET (rate,insert_dt)=(EXCLUDED.rate,now());"
-	stmt, err := repo.db.Prepare(sqlStr)
+	tx, err := repo.db.BeginTx(ctx, nil)
 	if err != nil {
 		return errors.Wrapf(err, ErrAdd)
 	}
+	defer func() {
+		if err != nil {
+			tx.Rollback() //nolint:errcheck
+		}
+	}()
 
-	result, err := stmt.ExecContext(ctx, vals...)
-	if err != nil {
+	sqlStr := "insert into public.currency (id, num_code, char_code, nominal, name, rate, base, provider, insert_dt) values "
+	histStr := "insert into public.currency_rate (id, rate_date, num_code, char_code, nominal, name, rate, base, provider) values "
+	var vals, histVals []interface{}
+	for i, row := range cs {
+		date := row.RateDate()
+		sqlStr += pgPlaceholders(i, 9) + ","
+		vals = append(vals, row.ID, row.NumCode, row.CharCode, nominal(row), row.Name, row.Value, row.Base, row.Provider, date)
+		histStr += pgPlaceholders(i, 9) + ","
+		histVals = append(histVals, row.ID, date, row.NumCode, row.CharCode, nominal(row), row.Name, row.Value, row.Base, row.Provider)
+	}
+	sqlStr = sqlStr[0 : len(sqlStr)-1]
+	// older rates (e.g. from backfill) go to history only and don't replace the latest ones
+	sqlStr += ` on conflict (id) do UPDATE
+				SET (num_code,char_code,nominal,name,rate,base,provider,insert_dt)=
+				(EXCLUDED.num_code,EXCLUDED.char_code,EXCLUDED.nominal,EXCLUDED.name,EXCLUDED.rate,EXCLUDED.base,EXCLUDED.provider,EXCLUDED.insert_dt)
+				where public.currency.insert_dt<=EXCLUDED.insert_dt;`
+	histStr = histStr[0 : len(histStr)-1]
+	histStr += ` on conflict (id, rate_date) do UPDATE
+				SET (num_code,char_code,nominal,name,rate,base,provider,insert_dt)=
+				(EXCLUDED.num_code,EXCLUDED.char_code,EXCLUDED.nominal,EXCLUDED.name,EXCLUDED.rate,EXCLUDED.base,EXCLUDED.provider,now());`
+
+	if _, err = tx.ExecContext(ctx, sqlStr, vals...); err != nil {
 		return errors.Wrapf(err, ErrAdd)
 	}
-
-	rows, err := result.RowsAffected()
-	if err != nil {
+	if _, err = tx.ExecContext(ctx, histStr, histVals...); err != nil {
 		return errors.Wrapf(err, ErrAdd)
 	}
-
-	if rows != int64(len(cs)) {
+	if err = tx.Commit(); err != nil {
 		return errors.Wrapf(err, ErrAdd)
 	}
 	return nil
 }
 
-func (repo *PGSRepo) GetByID(ctx context.Context, id string) (*entity.Currency, error) {
-	row := repo.db.QueryRowContext(ctx, `select id, name, rate 
+func (repo *PGSRepo) GetByID(ctx context.Context, id string) (c *entity.Currency, err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemPostgreSQL, "GetByID")
+	defer func() { tracing.End(span, err) }()
+
+	row := repo.db.QueryRowContext(ctx, `select id, num_code, char_code, nominal, name, rate, base, provider, insert_dt
 												from public.currency where id=$1;`, id)
-	if row == nil {
-		return nil, nil
-	}
+	return rowToCurrency(row, ErrGet)
+}
 
-	c := entity.Currency{Nominal: 1}
-	err := row.Scan(&c.ID, &c.Name, &c.Value)
-	if err != nil {
+func (repo *PGSRepo) GetByCharCode(ctx context.Context, code string) (c *entity.Currency, err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemPostgreSQL, "GetByCharCode")
+	defer func() { tracing.End(span, err) }()
+
+	row := repo.db.QueryRowContext(ctx, `select id, num_code, char_code, nominal, name, rate, base, provider, insert_dt
+												from public.currency where char_code=$1 order by insert_dt desc, id limit 1;`, code)
+	return rowToCurrency(row, ErrGet)
+}
+
+func (repo *PGSRepo) GetByNumCode(ctx context.Context, code int) (c *entity.Currency, err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemPostgreSQL, "GetByNumCode")
+	defer func() { tracing.End(span, err) }()
+
+	row := repo.db.QueryRowContext(ctx, `select id, num_code, char_code, nominal, name, rate, base, provider, insert_dt
+												from public.currency where num_code=$1 order by insert_dt desc, id limit 1;`, code)
+	return rowToCurrency(row, ErrGet)
+}
+
+func (repo *PGSRepo) GetNewestDate(ctx context.Context) (date time.Time, err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemPostgreSQL, "GetNewestDate")
+	defer func() { tracing.End(span, err) }()
+
+	row := repo.db.QueryRowContext(ctx, `select insert_dt from public.currency order by insert_dt desc limit 1;`)
+	return rowToDate(row, ErrGet)
+}
+
+func (repo *PGSRepo) GetByIDOnDate(ctx context.Context, id string, date time.Time) (c *entity.Currency, err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemPostgreSQL, "GetByIDOnDate")
+	defer func() { tracing.End(span, err) }()
+
+	row := repo.db.QueryRowContext(ctx, `select id, num_code, char_code, nominal, name, rate, base, provider, rate_date
+												from public.currency_rate where id=$1 and rate_date<=$2
+												order by rate_date desc limit 1;`, id, entity.Day(date))
+	return rowToCurrency(row, ErrGet)
+}
+
+func (repo *PGSRepo) GetHistory(ctx context.Context, id string, from, to time.Time) (cs []*entity.Currency, err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemPostgreSQL, "GetHistory")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := repo.db.QueryContext(ctx, `select id, num_code, char_code, nominal, name, rate, base, provider, rate_date
+												from public.currency_rate where id=$1 and rate_date between $2 and $3
+												order by rate_date;`, id, entity.Day(from), entity.Day(to))
+	if err != nil && err != sql.ErrNoRows {
 		return nil, SQLError(err, ErrGet)
 	}
-
-	return &c, nil
+	defer rows.Close()
+	return rowsToCurrencies(rows, ErrGet)
 }
 
-func (repo *PGSRepo) GetPage(ctx context.Context, limit, offset int) ([]*entity.Currency, error) {
-	rows, err := repo.db.QueryContext(ctx, `select id, name, rate 
+func (repo *PGSRepo) GetPage(ctx context.Context, limit, offset int) (cs []*entity.Currency, err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemPostgreSQL, "GetPage")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := repo.db.QueryContext(ctx, `select id, num_code, char_code, nominal, name, rate, base, provider, insert_dt
 												from public.currency order by id limit $1 offset $2;`, limit, offset)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, SQLError(err, ErrGet)
 	}
 	defer rows.Close()
-	return repo.rowsToCurrencies(rows, ErrGet)
+	return rowsToCurrencies(rows, ErrGet)
 }
 
-func (repo *PGSRepo) GetLazy(ctx context.Context, limit int, lastID string) ([]*entity.Currency, error) {
-	rows, err := repo.db.QueryContext(ctx, `select id, name, rate
+func (repo *PGSRepo) GetLazy(ctx context.Context, limit int, lastID string) (cs []*entity.Currency, err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemPostgreSQL, "GetLazy")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := repo.db.QueryContext(ctx, `select id, num_code, char_code, nominal, name, rate, base, provider, insert_dt
 												from public.currency where id>$1 order by id limit $2;`, lastID, limit)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, SQLError(err, ErrGet)
 	}
 	defer rows.Close()
-	return repo.rowsToCurrencies(rows, ErrGet)
+	return rowsToCurrencies(rows, ErrGet)
 }
 
 func (repo *PGSRepo) Connect(ctx context.Context, dsn string) (err error) {
@@ -105,32 +178,29 @@ func (repo *PGSRepo) Connect(ctx context.Context, dsn string) (err error) {
 	return nil
 }
 
+func (repo *PGSRepo) Ping(ctx context.Context) error {
+	return ping(ctx, repo.db)
+}
+
+// Migrate runs up, down or status command of the embedded schema migrations.
+func (repo *PGSRepo) Migrate(command string) error {
+	return migrate(repo.db, "postgres", "migrations/postgres", command)
+}
+
 func (repo *PGSRepo) Close() error {
 	return repo.db.Close()
 }
 
-func (repo *PGSRepo) rowsToCurrencies(rows *sql.Rows, errorString string) ([]*entity.Currency, error) {
-	var currencies []*entity.Currency
-	for rows.Next() {
-		c := entity.Currency{Nominal: 1}
-		err := rows.Scan(&c.ID, &c.Name, &c.Value)
-		if err != nil {
-			return nil, SQLError(err, errorString)
-		}
-		currencies = append(currencies, &c)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, SQLError(err, errorString)
-	}
-	return currencies, nil
+// DB returns the connection pool, e.g. to collect its stats.
+func (repo *PGSRepo) DB() *sql.DB {
+	return repo.db
 }
 
-func SQLError(err error, message string) error {
-	switch err {
-	case sql.ErrNoRows:
-		return nil
-	default:
-		return errors.Wrap(err, message)
+// pgPlaceholders returns ($1,$2,...) placeholders of the row with cols columns in a multi row insert.
+func pgPlaceholders(row, cols int) string {
+	ps := make([]string, cols)
+	for i := range ps {
+		ps[i] = fmt.Sprintf("$%v", row*cols+i+1)
 	}
+	return "(" + strings.Join(ps, ",") + ")"
 }
@@ -4,9 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
@@ -15,15 +17,23 @@ import (
 
 var (
 	testID       = "R01020A"
+	testNumCode  = 944
+	testCharCode = "AZN"
 	testName     = "Азербайджанский манат"
-	testRate     = 44.7113
+	testRate     = decimal.RequireFromString("44.7113")
+	testBase     = "RUB"
+	testProvider = "cbr"
+	testDate     = time.Date(2020, 3, 3, 0, 0, 0, 0, time.UTC)
 	testCurrency = entity.Currency{
 		ID:       testID,
-		NumCode:  0,
-		CharCode: "",
+		NumCode:  testNumCode,
+		CharCode: testCharCode,
 		Nominal:  1,
 		Name:     testName,
 		Value:    testRate,
+		Base:     testBase,
+		Date:     testDate,
+		Provider: testProvider,
 	}
 	testLimit  = 3
 	testOffset = 1
@@ -59,10 +69,10 @@ func (s *Suite) AfterTest(_, _ string) {
 func (s *Suite) TestPGSRepo_GetByID() {
 	ctx := context.TODO()
 	s.Run("good test: get currency by id", func() {
-		rows := sqlmock.NewRows([]string{"id", "name", "rate"}).
-			AddRow(testID, testName, testRate)
+		rows := sqlmock.NewRows([]string{"id", "num_code", "char_code", "nominal", "name", "rate", "base", "provider", "insert_dt"}).
+			AddRow(testID, testNumCode, testCharCode, 1, testName, testRate, testBase, testProvider, testDate)
 
-		s.mock.ExpectQuery(`select id, name, rate from`).
+		s.mock.ExpectQuery(`select id, num_code, char_code, nominal, name, rate, base, provider, insert_dt from`).
 			WithArgs(testID).
 			WillReturnRows(rows)
 
@@ -71,18 +81,18 @@ func (s *Suite) TestPGSRepo_GetByID() {
 		require.Equal(s.T(), &testCurrency, c)
 	})
 	s.Run("no rows: get event by id", func() {
-		rows := sqlmock.NewRows([]string{"id", "name", "rate"})
-		s.mock.ExpectQuery(`select id, name, rate from`).
+		rows := sqlmock.NewRows([]string{"id", "num_code", "char_code", "nominal", "name", "rate", "base", "provider", "insert_dt"})
+		s.mock.ExpectQuery(`select id, num_code, char_code, nominal, name, rate, base, provider, insert_dt from`).
 			WithArgs(testID).
 			WillReturnRows(rows)
 
 		c, err := s.repo.GetByID(ctx, testID)
 
-		require.Nil(s.T(), err)
+		require.Equal(s.T(), entity.ErrNotFound, err)
 		require.Nil(s.T(), c)
 	})
 	s.Run("return error: get event by id", func() {
-		s.mock.ExpectQuery(`select id, name, rate from`).
+		s.mock.ExpectQuery(`select id, num_code, char_code, nominal, name, rate, base, provider, insert_dt from`).
 			WillReturnError(sql.ErrConnDone)
 
 		c, err := s.repo.GetByID(ctx, testID)
@@ -95,12 +105,12 @@ func (s *Suite) TestPGSRepo_GetByID() {
 func (s *Suite) TestPGSRepo_GetPage() {
 	ctx := context.TODO()
 	s.Run("good test: pagination", func() {
-		rows := sqlmock.NewRows([]string{"id", "name", "rate"}).
-			AddRow(testID, testName, testRate).
-			AddRow(testID, testName, testRate).
-			AddRow(testID, testName, testRate)
+		rows := sqlmock.NewRows([]string{"id", "num_code", "char_code", "nominal", "name", "rate", "base", "provider", "insert_dt"}).
+			AddRow(testID, testNumCode, testCharCode, 1, testName, testRate, testBase, testProvider, testDate).
+			AddRow(testID, testNumCode, testCharCode, 1, testName, testRate, testBase, testProvider, testDate).
+			AddRow(testID, testNumCode, testCharCode, 1, testName, testRate, testBase, testProvider, testDate)
 
-		s.mock.ExpectQuery(`select id, name, rate from`).
+		s.mock.ExpectQuery(`select id, num_code, char_code, nominal, name, rate, base, provider, insert_dt from`).
 			WithArgs(testLimit, testOffset).
 			WillReturnRows(rows)
 
@@ -109,8 +119,8 @@ func (s *Suite) TestPGSRepo_GetPage() {
 		require.Equal(s.T(), []*entity.Currency{&testCurrency, &testCurrency, &testCurrency}, cs)
 	})
 	s.Run("no rows: pagination", func() {
-		rows := sqlmock.NewRows([]string{"id", "name", "rate"})
-		s.mock.ExpectQuery(`select id, name, rate from`).
+		rows := sqlmock.NewRows([]string{"id", "num_code", "char_code", "nominal", "name", "rate", "base", "provider", "insert_dt"})
+		s.mock.ExpectQuery(`select id, num_code, char_code, nominal, name, rate, base, provider, insert_dt from`).
 			WithArgs(testLimit, testOffset).
 			WillReturnRows(rows)
 
@@ -120,7 +130,7 @@ func (s *Suite) TestPGSRepo_GetPage() {
 		require.Nil(s.T(), cs)
 	})
 	s.Run("return error: pagination", func() {
-		s.mock.ExpectQuery(`select id, name, rate from`).
+		s.mock.ExpectQuery(`select id, num_code, char_code, nominal, name, rate, base, provider, insert_dt from`).
 			WillReturnError(sql.ErrConnDone)
 
 		cs, err := s.repo.GetPage(ctx, testLimit, testOffset)
@@ -133,12 +143,12 @@ func (s *Suite) TestPGSRepo_GetPage() {
 func (s *Suite) TestPGSRepo_GetLazy() {
 	ctx := context.TODO()
 	s.Run("good test: lazy load", func() {
-		rows := sqlmock.NewRows([]string{"id", "name", "rate"}).
-			AddRow(testID, testName, testRate).
-			AddRow(testID, testName, testRate).
-			AddRow(testID, testName, testRate)
+		rows := sqlmock.NewRows([]string{"id", "num_code", "char_code", "nominal", "name", "rate", "base", "provider", "insert_dt"}).
+			AddRow(testID, testNumCode, testCharCode, 1, testName, testRate, testBase, testProvider, testDate).
+			AddRow(testID, testNumCode, testCharCode, 1, testName, testRate, testBase, testProvider, testDate).
+			AddRow(testID, testNumCode, testCharCode, 1, testName, testRate, testBase, testProvider, testDate)
 
-		s.mock.ExpectQuery(`select id, name, rate from`).
+		s.mock.ExpectQuery(`select id, num_code, char_code, nominal, name, rate, base, provider, insert_dt from`).
 			WithArgs(testID, testLimit).
 			WillReturnRows(rows)
 
@@ -147,8 +157,8 @@ func (s *Suite) TestPGSRepo_GetLazy() {
 		require.Equal(s.T(), []*entity.Currency{&testCurrency, &testCurrency, &testCurrency}, cs)
 	})
 	s.Run("no rows: lazy load", func() {
-		rows := sqlmock.NewRows([]string{"id", "name", "rate"})
-		s.mock.ExpectQuery(`select id, name, rate from`).
+		rows := sqlmock.NewRows([]string{"id", "num_code", "char_code", "nominal", "name", "rate", "base", "provider", "insert_dt"})
+		s.mock.ExpectQuery(`select id, num_code, char_code, nominal, name, rate, base, provider, insert_dt from`).
 			WithArgs(testID, testLimit).
 			WillReturnRows(rows)
 
@@ -158,7 +168,7 @@ func (s *Suite) TestPGSRepo_GetLazy() {
 		require.Nil(s.T(), cs)
 	})
 	s.Run("return error: lazy load", func() {
-		s.mock.ExpectQuery(`select id, name, rate from`).
+		s.mock.ExpectQuery(`select id, num_code, char_code, nominal, name, rate, base, provider, insert_dt from`).
 			WillReturnError(sql.ErrConnDone)
 
 		cs, err := s.repo.GetLazy(ctx, testLimit, testID)
@@ -167,28 +177,134 @@ func (s *Suite) TestPGSRepo_GetLazy() {
 		require.Truef(s.T(), errors.Is(err, sql.ErrConnDone), "GetByID not return cause error")
 	})
 }
+func (s *Suite) TestPGSRepo_GetByCode() {
+	ctx := context.TODO()
+	s.Run("good test: get currency by char code", func() {
+		rows := sqlmock.NewRows([]string{"id", "num_code", "char_code", "nominal", "name", "rate", "base", "provider", "insert_dt"}).
+			AddRow(testID, testNumCode, testCharCode, 1, testName, testRate, testBase, testProvider, testDate)
+
+		s.mock.ExpectQuery(`select (.+) from public.currency where char_code=(.+) order by insert_dt desc`).
+			WithArgs(testCharCode).
+			WillReturnRows(rows)
+
+		c, err := s.repo.GetByCharCode(ctx, testCharCode)
+		require.Nil(s.T(), err)
+		require.Equal(s.T(), &testCurrency, c)
+	})
+	s.Run("good test: get currency by num code", func() {
+		rows := sqlmock.NewRows([]string{"id", "num_code", "char_code", "nominal", "name", "rate", "base", "provider", "insert_dt"}).
+			AddRow(testID, testNumCode, testCharCode, 1, testName, testRate, testBase, testProvider, testDate)
+
+		s.mock.ExpectQuery(`select (.+) from public.currency where num_code=(.+) order by insert_dt desc`).
+			WithArgs(testNumCode).
+			WillReturnRows(rows)
+
+		c, err := s.repo.GetByNumCode(ctx, testNumCode)
+		require.Nil(s.T(), err)
+		require.Equal(s.T(), &testCurrency, c)
+	})
+}
+
+func (s *Suite) TestPGSRepo_GetNewestDate() {
+	ctx := context.TODO()
+	s.Run("good test: get newest rate date", func() {
+		s.mock.ExpectQuery(`select insert_dt from public.currency order by insert_dt desc limit 1`).
+			WillReturnRows(sqlmock.NewRows([]string{"insert_dt"}).AddRow(testDate))
+
+		date, err := s.repo.GetNewestDate(ctx)
+		require.Nil(s.T(), err)
+		require.Equal(s.T(), testDate, date)
+	})
+	s.Run("good test: no rates", func() {
+		s.mock.ExpectQuery(`select insert_dt from public.currency`).
+			WillReturnRows(sqlmock.NewRows([]string{"insert_dt"}))
+
+		date, err := s.repo.GetNewestDate(ctx)
+		require.Nil(s.T(), err)
+		require.True(s.T(), date.IsZero())
+	})
+}
+
+func (s *Suite) TestPGSRepo_GetByIDOnDate() {
+	ctx := context.TODO()
+	s.Run("good test: get currency by id on date", func() {
+		rows := sqlmock.NewRows([]string{"id", "num_code", "char_code", "nominal", "name", "rate", "base", "provider", "rate_date"}).
+			AddRow(testID, testNumCode, testCharCode, 1, testName, testRate, testBase, testProvider, testDate)
+
+		s.mock.ExpectQuery(`select id, num_code, char_code, nominal, name, rate, base, provider, rate_date from public.currency_rate`).
+			WithArgs(testID, testDate).
+			WillReturnRows(rows)
+
+		c, err := s.repo.GetByIDOnDate(ctx, testID, testDate)
+		require.Nil(s.T(), err)
+		require.Equal(s.T(), &testCurrency, c)
+	})
+	s.Run("no rows: get currency by id on date", func() {
+		rows := sqlmock.NewRows([]string{"id", "num_code", "char_code", "nominal", "name", "rate", "base", "provider", "rate_date"})
+		s.mock.ExpectQuery(`select id, num_code, char_code, nominal, name, rate, base, provider, rate_date from public.currency_rate`).
+			WithArgs(testID, testDate).
+			WillReturnRows(rows)
+
+		c, err := s.repo.GetByIDOnDate(ctx, testID, testDate)
+
+		require.Equal(s.T(), entity.ErrNotFound, err)
+		require.Nil(s.T(), c)
+	})
+}
+
+func (s *Suite) TestPGSRepo_GetHistory() {
+	ctx := context.TODO()
+	from := testDate.AddDate(0, 0, -1)
+	s.Run("good test: history", func() {
+		rows := sqlmock.NewRows([]string{"id", "num_code", "char_code", "nominal", "name", "rate", "base", "provider", "rate_date"}).
+			AddRow(testID, testNumCode, testCharCode, 1, testName, testRate, testBase, testProvider, testDate).
+			AddRow(testID, testNumCode, testCharCode, 1, testName, testRate, testBase, testProvider, testDate)
+
+		s.mock.ExpectQuery(`select id, num_code, char_code, nominal, name, rate, base, provider, rate_date from public.currency_rate`).
+			WithArgs(testID, from, testDate).
+			WillReturnRows(rows)
+
+		cs, err := s.repo.GetHistory(ctx, testID, from, testDate)
+		require.Nil(s.T(), err)
+		require.Equal(s.T(), []*entity.Currency{&testCurrency, &testCurrency}, cs)
+	})
+	s.Run("return error: history", func() {
+		s.mock.ExpectQuery(`select id, num_code, char_code, nominal, name, rate, base, provider, rate_date from public.currency_rate`).
+			WillReturnError(sql.ErrConnDone)
+
+		cs, err := s.repo.GetHistory(ctx, testID, from, testDate)
+
+		require.Nil(s.T(), cs)
+		require.Truef(s.T(), errors.Is(err, sql.ErrConnDone), "GetHistory not return cause error")
+	})
+}
+
 func (s *Suite) TestPGSRepo_SetAll() {
 	ctx := context.TODO()
 	s.Run("good test: save 1 currency to db", func() {
-		s.mock.ExpectPrepare(`insert into public.currency`).ExpectExec().
-			WithArgs(testID, testName, testRate).
+		s.mock.ExpectBegin()
+		s.mock.ExpectExec(`insert into public.currency `).
+			WithArgs(testID, testNumCode, testCharCode, 1, testName, testRate, testBase, testProvider, testDate).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		s.mock.ExpectExec(`insert into public.currency_rate`).
+			WithArgs(testID, testDate, testNumCode, testCharCode, 1, testName, testRate, testBase, testProvider).
 			WillReturnResult(sqlmock.NewResult(0, 1))
+		s.mock.ExpectCommit()
 
 		err := s.repo.SetAll(ctx, []*entity.Currency{&testCurrency})
 		require.Nil(s.T(), err)
 	})
 	s.Run("good test: save 0 currency to db", func() {
-		s.mock.ExpectPrepare(`insert into public.currency`).ExpectExec().
-			WillReturnResult(sqlmock.NewResult(0, 0))
-
 		err := s.repo.SetAll(ctx, []*entity.Currency{})
 		require.Nil(s.T(), err)
 	})
 	s.Run("return error: save currency to db", func() {
-		s.mock.ExpectPrepare(`insert into public.currency`).ExpectExec().
+		s.mock.ExpectBegin()
+		s.mock.ExpectExec(`insert into public.currency `).
 			WillReturnError(sql.ErrConnDone)
+		s.mock.ExpectRollback()
 
-		err := s.repo.SetAll(ctx, []*entity.Currency{})
+		err := s.repo.SetAll(ctx, []*entity.Currency{&testCurrency})
 
 		require.Truef(s.T(), errors.Is(err, sql.ErrConnDone), "GetByID not return cause error")
 	})
@@ -1,4 +1,4 @@
-DROP DATABASE IF EXISTS currencier;
-CREATE DATABASE currencier;
-CREATE USER igor WITH encrypted password 'igor';
-GRANT ALL PRIVILEGES ON DATABASE currencier to igor;
+-- Creates the database owned by the user the postgres container is started with,
+-- the schema is applied by `currencier migrate up` or `currencier start --migrate`.
+SELECT 'CREATE DATABASE currencier'
+WHERE NOT EXISTS (SELECT FROM pg_database WHERE datname = 'currencier')\gexec
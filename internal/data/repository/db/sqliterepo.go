@@ -0,0 +1,187 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+
+	"github.com/redselig/currencier/internal/data/tracing"
+	"github.com/redselig/currencier/internal/domain/entity"
+)
+
+// SQLiteDialect is the db dialect and the name of the pure Go sqlite driver.
+const SQLiteDialect = "sqlite"
+
+var _ entity.CurrencyInternalRepository = (*SQLiteRepo)(nil)
+
+// SQLiteRepo stores currencies in a single file sqlite database,
+// rates are kept as text to stay exact and dates as UTC days.
+type SQLiteRepo struct {
+	db *sql.DB
+}
+
+func NewSQLiteRepo(dsn string) (*SQLiteRepo, error) {
+	db, err := sql.Open(SQLiteDialect, dsn)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't create connect to db with dsn %v by driver %v", dsn, SQLiteDialect)
+	}
+	// sqlite allows only one writer, so don't let the pool fight for the lock
+	db.SetMaxOpenConns(1)
+	return &SQLiteRepo{
+		db: db,
+	}, nil
+}
+
+func (repo *SQLiteRepo) SetAll(ctx context.Context, cs []*entity.Currency) (err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemSqlite, "SetAll")
+	defer func() { tracing.End(span, err) }()
+
+	if len(cs) == 0 {
+		return nil
+	}
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrapf(err, ErrAdd)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback() //nolint:errcheck
+		}
+	}()
+
+	placeholders := strings.TrimSuffix(strings.Repeat("(?,?,?,?,?,?,?,?,?),", len(cs)), ",")
+	var vals, histVals []interface{}
+	for _, row := range cs {
+		date := row.RateDate()
+		vals = append(vals, row.ID, row.NumCode, row.CharCode, nominal(row), row.Name, row.Value, row.Base, row.Provider, date)
+		histVals = append(histVals, row.ID, date, row.NumCode, row.CharCode, nominal(row), row.Name, row.Value, row.Base, row.Provider)
+	}
+	// older rates (e.g. from backfill) go to history only and don't replace the latest ones
+	sqlStr := `insert into currency (id, num_code, char_code, nominal, name, rate, base, provider, insert_dt) values ` + placeholders + `
+				on conflict (id) do update set num_code=excluded.num_code, char_code=excluded.char_code,
+				nominal=excluded.nominal, name=excluded.name, rate=excluded.rate, base=excluded.base, provider=excluded.provider,
+				insert_dt=excluded.insert_dt
+				where currency.insert_dt<=excluded.insert_dt;`
+	histStr := `insert into currency_rate (id, rate_date, num_code, char_code, nominal, name, rate, base, provider) values ` + placeholders + `
+				on conflict (id, rate_date) do update set num_code=excluded.num_code, char_code=excluded.char_code,
+				nominal=excluded.nominal, name=excluded.name, rate=excluded.rate, base=excluded.base, provider=excluded.provider,
+				insert_dt=CURRENT_TIMESTAMP;`
+
+	if _, err = tx.ExecContext(ctx, sqlStr, vals...); err != nil {
+		return errors.Wrapf(err, ErrAdd)
+	}
+	if _, err = tx.ExecContext(ctx, histStr, histVals...); err != nil {
+		return errors.Wrapf(err, ErrAdd)
+	}
+	if err = tx.Commit(); err != nil {
+		return errors.Wrapf(err, ErrAdd)
+	}
+	return nil
+}
+
+func (repo *SQLiteRepo) GetByID(ctx context.Context, id string) (c *entity.Currency, err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemSqlite, "GetByID")
+	defer func() { tracing.End(span, err) }()
+
+	row := repo.db.QueryRowContext(ctx, `select id, num_code, char_code, nominal, name, rate, base, provider, insert_dt
+												from currency where id=?;`, id)
+	return rowToCurrency(row, ErrGet)
+}
+
+func (repo *SQLiteRepo) GetByCharCode(ctx context.Context, code string) (c *entity.Currency, err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemSqlite, "GetByCharCode")
+	defer func() { tracing.End(span, err) }()
+
+	row := repo.db.QueryRowContext(ctx, `select id, num_code, char_code, nominal, name, rate, base, provider, insert_dt
+												from currency where char_code=? order by insert_dt desc, id limit 1;`, code)
+	return rowToCurrency(row, ErrGet)
+}
+
+func (repo *SQLiteRepo) GetByNumCode(ctx context.Context, code int) (c *entity.Currency, err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemSqlite, "GetByNumCode")
+	defer func() { tracing.End(span, err) }()
+
+	row := repo.db.QueryRowContext(ctx, `select id, num_code, char_code, nominal, name, rate, base, provider, insert_dt
+												from currency where num_code=? order by insert_dt desc, id limit 1;`, code)
+	return rowToCurrency(row, ErrGet)
+}
+
+func (repo *SQLiteRepo) GetNewestDate(ctx context.Context) (date time.Time, err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemSqlite, "GetNewestDate")
+	defer func() { tracing.End(span, err) }()
+
+	row := repo.db.QueryRowContext(ctx, `select insert_dt from currency order by insert_dt desc limit 1;`)
+	return rowToDate(row, ErrGet)
+}
+
+func (repo *SQLiteRepo) GetByIDOnDate(ctx context.Context, id string, date time.Time) (c *entity.Currency, err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemSqlite, "GetByIDOnDate")
+	defer func() { tracing.End(span, err) }()
+
+	row := repo.db.QueryRowContext(ctx, `select id, num_code, char_code, nominal, name, rate, base, provider, rate_date
+												from currency_rate where id=? and rate_date<=?
+												order by rate_date desc limit 1;`, id, entity.Day(date))
+	return rowToCurrency(row, ErrGet)
+}
+
+func (repo *SQLiteRepo) GetHistory(ctx context.Context, id string, from, to time.Time) (cs []*entity.Currency, err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemSqlite, "GetHistory")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := repo.db.QueryContext(ctx, `select id, num_code, char_code, nominal, name, rate, base, provider, rate_date
+												from currency_rate where id=? and rate_date between ? and ?
+												order by rate_date;`, id, entity.Day(from), entity.Day(to))
+	if err != nil && err != sql.ErrNoRows {
+		return nil, SQLError(err, ErrGet)
+	}
+	defer rows.Close()
+	return rowsToCurrencies(rows, ErrGet)
+}
+
+func (repo *SQLiteRepo) GetPage(ctx context.Context, limit, offset int) (cs []*entity.Currency, err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemSqlite, "GetPage")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := repo.db.QueryContext(ctx, `select id, num_code, char_code, nominal, name, rate, base, provider, insert_dt
+												from currency order by id limit ? offset ?;`, limit, offset)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, SQLError(err, ErrGet)
+	}
+	defer rows.Close()
+	return rowsToCurrencies(rows, ErrGet)
+}
+
+func (repo *SQLiteRepo) GetLazy(ctx context.Context, limit int, lastID string) (cs []*entity.Currency, err error) {
+	ctx, span := startQuery(ctx, semconv.DBSystemSqlite, "GetLazy")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := repo.db.QueryContext(ctx, `select id, num_code, char_code, nominal, name, rate, base, provider, insert_dt
+												from currency where id>? order by id limit ?;`, lastID, limit)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, SQLError(err, ErrGet)
+	}
+	defer rows.Close()
+	return rowsToCurrencies(rows, ErrGet)
+}
+
+func (repo *SQLiteRepo) Ping(ctx context.Context) error {
+	return ping(ctx, repo.db)
+}
+
+// Migrate runs up, down or status command of the embedded schema migrations.
+func (repo *SQLiteRepo) Migrate(command string) error {
+	return migrate(repo.db, "sqlite3", "migrations/sqlite", command)
+}
+
+func (repo *SQLiteRepo) Close() error {
+	return repo.db.Close()
+}
+
+// DB returns the connection pool, e.g. to collect its stats.
+func (repo *SQLiteRepo) DB() *sql.DB {
+	return repo.db
+}
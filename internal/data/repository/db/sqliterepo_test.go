@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	"github.com/redselig/currencier/internal/domain/entity"
+)
+
+func newTestSQLiteRepo(t *testing.T) *SQLiteRepo {
+	repo, err := NewSQLiteRepo(":memory:")
+	require.Nil(t, err)
+	require.Nil(t, repo.Migrate(MigrateUp))
+	return repo
+}
+
+func TestSQLiteRepo(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestSQLiteRepo(t)
+	defer repo.Close()
+	require.Nil(t, repo.Ping(ctx))
+	newest, err := repo.GetNewestDate(ctx)
+	require.Nil(t, err)
+	require.True(t, newest.IsZero())
+
+	older := testCurrency
+	older.Date = testDate.AddDate(0, 0, -1)
+	require.Nil(t, repo.SetAll(ctx, []*entity.Currency{&testCurrency}))
+	require.Nil(t, repo.SetAll(ctx, []*entity.Currency{&older}))
+	require.Nil(t, repo.SetAll(ctx, []*entity.Currency{&testCurrency}))
+
+	t.Run("get latest", func(t *testing.T) {
+		c, err := repo.GetByID(ctx, testID)
+		require.Nil(t, err)
+		require.Equal(t, &testCurrency, c)
+
+		c, err = repo.GetByCharCode(ctx, testCharCode)
+		require.Nil(t, err)
+		require.Equal(t, testID, c.ID)
+
+		c, err = repo.GetByNumCode(ctx, testNumCode)
+		require.Nil(t, err)
+		require.Equal(t, testID, c.ID)
+
+		c, err = repo.GetByID(ctx, "NOPE")
+		require.Equal(t, entity.ErrNotFound, err)
+		require.Nil(t, c)
+	})
+	t.Run("get freshest by code", func(t *testing.T) {
+		repo := newTestSQLiteRepo(t)
+		defer repo.Close()
+
+		fresh := testCurrency
+		fresh.ID = "A" + testID
+		fresh.Base = "EUR"
+		fresh.Date = testDate.AddDate(0, 0, 1)
+		require.Nil(t, repo.SetAll(ctx, []*entity.Currency{&testCurrency, &fresh}))
+
+		c, err := repo.GetByCharCode(ctx, testCharCode)
+		require.Nil(t, err)
+		require.Equal(t, fresh.ID, c.ID)
+
+		c, err = repo.GetByNumCode(ctx, testNumCode)
+		require.Nil(t, err)
+		require.Equal(t, fresh.ID, c.ID)
+
+		newest, err := repo.GetNewestDate(ctx)
+		require.Nil(t, err)
+		require.True(t, fresh.Date.Equal(newest))
+	})
+	t.Run("get history", func(t *testing.T) {
+		c, err := repo.GetByIDOnDate(ctx, testID, older.Date.Add(12*time.Hour))
+		require.Nil(t, err)
+		require.Equal(t, older.Date, c.Date)
+
+		cs, err := repo.GetHistory(ctx, testID, older.Date, testDate)
+		require.Nil(t, err)
+		require.Len(t, cs, 2)
+		require.Equal(t, older.Date, cs[0].Date)
+		require.Equal(t, testDate, cs[1].Date)
+	})
+	t.Run("pagination", func(t *testing.T) {
+		cs, err := repo.GetPage(ctx, testLimit, 0)
+		require.Nil(t, err)
+		require.Len(t, cs, 1)
+
+		cs, err = repo.GetLazy(ctx, testLimit, testID)
+		require.Nil(t, err)
+		require.Nil(t, cs)
+	})
+}
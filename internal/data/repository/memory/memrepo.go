@@ -0,0 +1,188 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redselig/currencier/internal/domain/entity"
+)
+
+// Dialect is the db dialect the in-memory repository is selected by.
+const Dialect = "memory"
+
+var _ entity.CurrencyInternalRepository = (*MemRepo)(nil)
+
+// MemRepo keeps the latest currencies and their history in memory, it's safe for concurrent use.
+type MemRepo struct {
+	mu      sync.RWMutex
+	latest  map[string]*entity.Currency
+	history map[string]map[time.Time]*entity.Currency
+}
+
+func NewMemRepo() *MemRepo {
+	return &MemRepo{
+		latest:  make(map[string]*entity.Currency),
+		history: make(map[string]map[time.Time]*entity.Currency),
+	}
+}
+
+func (repo *MemRepo) SetAll(ctx context.Context, cs []*entity.Currency) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, row := range cs {
+		c := *row
+		c.Date = row.RateDate()
+		if c.Nominal < 1 {
+			c.Nominal = 1
+		}
+		if _, ok := repo.history[c.ID]; !ok {
+			repo.history[c.ID] = make(map[time.Time]*entity.Currency)
+		}
+		repo.history[c.ID][c.Date] = &c
+		// older rates (e.g. from backfill) go to history only and don't replace the latest ones
+		if last, ok := repo.latest[c.ID]; !ok || !last.Date.After(c.Date) {
+			repo.latest[c.ID] = &c
+		}
+	}
+	return nil
+}
+
+func (repo *MemRepo) GetByID(ctx context.Context, id string) (*entity.Currency, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	return found(clone(repo.latest[id]))
+}
+
+func (repo *MemRepo) GetByCharCode(ctx context.Context, code string) (*entity.Currency, error) {
+	return found(repo.find(func(c *entity.Currency) bool { return c.CharCode == code }))
+}
+
+func (repo *MemRepo) GetByNumCode(ctx context.Context, code int) (*entity.Currency, error) {
+	return found(repo.find(func(c *entity.Currency) bool { return c.NumCode == code }))
+}
+
+func (repo *MemRepo) GetNewestDate(ctx context.Context) (time.Time, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	var newest time.Time
+	for _, c := range repo.latest {
+		if c.Date.After(newest) {
+			newest = c.Date
+		}
+	}
+	return newest, nil
+}
+
+func (repo *MemRepo) GetByIDOnDate(ctx context.Context, id string, date time.Time) (*entity.Currency, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	date = entity.Day(date)
+	var last *entity.Currency
+	for d, c := range repo.history[id] {
+		if d.After(date) {
+			continue
+		}
+		if last == nil || d.After(last.Date) {
+			last = c
+		}
+	}
+	return found(clone(last))
+}
+
+func (repo *MemRepo) GetHistory(ctx context.Context, id string, from, to time.Time) ([]*entity.Currency, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	from, to = entity.Day(from), entity.Day(to)
+	var currencies []*entity.Currency
+	for d, c := range repo.history[id] {
+		if d.Before(from) || d.After(to) {
+			continue
+		}
+		currencies = append(currencies, clone(c))
+	}
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i].Date.Before(currencies[j].Date) })
+	return currencies, nil
+}
+
+func (repo *MemRepo) GetPage(ctx context.Context, limit, offset int) ([]*entity.Currency, error) {
+	sorted := repo.sorted()
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(sorted) {
+		return nil, nil
+	}
+	return head(sorted[offset:], limit), nil
+}
+
+func (repo *MemRepo) GetLazy(ctx context.Context, limit int, lastID string) ([]*entity.Currency, error) {
+	sorted := repo.sorted()
+	i := sort.Search(len(sorted), func(i int) bool { return sorted[i].ID > lastID })
+	if i >= len(sorted) {
+		return nil, nil
+	}
+	return head(sorted[i:], limit), nil
+}
+
+func (repo *MemRepo) Close() error {
+	return nil
+}
+
+// found returns entity.ErrNotFound if there is no currency.
+func found(c *entity.Currency) (*entity.Currency, error) {
+	if c == nil {
+		return nil, entity.ErrNotFound
+	}
+	return c, nil
+}
+
+// find returns the freshest latest currency matching the predicate, the first by id of the equally fresh ones,
+// so the rates of the provider that served the last update win over the stale rates of the others.
+func (repo *MemRepo) find(match func(c *entity.Currency) bool) *entity.Currency {
+	var fresh *entity.Currency
+	for _, c := range repo.sorted() {
+		if match(c) && (fresh == nil || c.Date.After(fresh.Date)) {
+			fresh = c
+		}
+	}
+	return fresh
+}
+
+// sorted returns copies of the latest currencies ordered by id.
+func (repo *MemRepo) sorted() []*entity.Currency {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	currencies := make([]*entity.Currency, 0, len(repo.latest))
+	for _, c := range repo.latest {
+		currencies = append(currencies, clone(c))
+	}
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i].ID < currencies[j].ID })
+	return currencies
+}
+
+// head returns at most limit first currencies, none if limit is negative.
+func head(cs []*entity.Currency, limit int) []*entity.Currency {
+	if limit < 0 {
+		limit = 0
+	}
+	if limit < len(cs) {
+		return cs[:limit]
+	}
+	return cs
+}
+
+func clone(c *entity.Currency) *entity.Currency {
+	if c == nil {
+		return nil
+	}
+	cp := *c
+	return &cp
+}
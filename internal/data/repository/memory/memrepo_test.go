@@ -0,0 +1,136 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redselig/currencier/internal/domain/entity"
+)
+
+var (
+	testDate = time.Date(2020, 3, 3, 0, 0, 0, 0, time.UTC)
+	testAZN  = entity.Currency{
+		ID:       "R01020A",
+		NumCode:  944,
+		CharCode: "AZN",
+		Nominal:  1,
+		Name:     "Азербайджанский манат",
+		Value:    decimal.RequireFromString("39.1256"),
+		Date:     testDate,
+	}
+	testUSD = entity.Currency{
+		ID:       "R01235",
+		NumCode:  840,
+		CharCode: "USD",
+		Nominal:  1,
+		Name:     "Доллар США",
+		Value:    decimal.RequireFromString("66.3274"),
+		Date:     testDate,
+	}
+)
+
+func TestMemRepo(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemRepo()
+
+	oldUSD := testUSD
+	oldUSD.Value = decimal.RequireFromString("65.5")
+	oldUSD.Date = testDate.AddDate(0, 0, -1)
+	require.Nil(t, repo.SetAll(ctx, []*entity.Currency{&testAZN, &testUSD}))
+	require.Nil(t, repo.SetAll(ctx, []*entity.Currency{&oldUSD}))
+
+	t.Run("get latest", func(t *testing.T) {
+		c, err := repo.GetByID(ctx, testUSD.ID)
+		require.Nil(t, err)
+		require.Equal(t, &testUSD, c)
+
+		c, err = repo.GetByCharCode(ctx, "AZN")
+		require.Nil(t, err)
+		require.Equal(t, &testAZN, c)
+
+		c, err = repo.GetByNumCode(ctx, 840)
+		require.Nil(t, err)
+		require.Equal(t, &testUSD, c)
+
+		c, err = repo.GetByID(ctx, "NOPE")
+		require.Equal(t, entity.ErrNotFound, err)
+		require.Nil(t, c)
+	})
+	t.Run("get freshest by code", func(t *testing.T) {
+		repo := NewMemRepo()
+		ecbUSD := entity.Currency{ID: "USD", NumCode: 840, CharCode: "USD", Nominal: 1, Name: "USD",
+			Value: decimal.RequireFromString("0.91"), Base: "EUR", Date: testDate.AddDate(0, 0, 1)}
+		require.Nil(t, repo.SetAll(ctx, []*entity.Currency{&testUSD, &ecbUSD}))
+
+		c, err := repo.GetByCharCode(ctx, "USD")
+		require.Nil(t, err)
+		require.Equal(t, &ecbUSD, c)
+
+		c, err = repo.GetByNumCode(ctx, 840)
+		require.Nil(t, err)
+		require.Equal(t, &ecbUSD, c)
+
+		newest, err := repo.GetNewestDate(ctx)
+		require.Nil(t, err)
+		require.Equal(t, ecbUSD.Date, newest)
+	})
+	t.Run("get history", func(t *testing.T) {
+		c, err := repo.GetByIDOnDate(ctx, testUSD.ID, testDate.AddDate(0, 0, -1))
+		require.Nil(t, err)
+		require.Equal(t, &oldUSD, c)
+
+		c, err = repo.GetByIDOnDate(ctx, testUSD.ID, testDate.AddDate(0, 0, 10))
+		require.Nil(t, err)
+		require.Equal(t, &testUSD, c)
+
+		cs, err := repo.GetHistory(ctx, testUSD.ID, testDate.AddDate(0, 0, -7), testDate)
+		require.Nil(t, err)
+		require.Equal(t, []*entity.Currency{&oldUSD, &testUSD}, cs)
+
+		cs, err = repo.GetHistory(ctx, testUSD.ID, oldUSD.Date.Add(12*time.Hour), testDate.Add(time.Hour))
+		require.Nil(t, err)
+		require.Equal(t, []*entity.Currency{&oldUSD, &testUSD}, cs)
+	})
+	t.Run("pagination", func(t *testing.T) {
+		cs, err := repo.GetPage(ctx, 1, 1)
+		require.Nil(t, err)
+		require.Equal(t, []*entity.Currency{&testUSD}, cs)
+
+		cs, err = repo.GetPage(ctx, 10, 2)
+		require.Nil(t, err)
+		require.Nil(t, cs)
+
+		cs, err = repo.GetLazy(ctx, 10, testAZN.ID)
+		require.Nil(t, err)
+		require.Equal(t, []*entity.Currency{&testUSD}, cs)
+
+		cs, err = repo.GetPage(ctx, 1, -1)
+		require.Nil(t, err)
+		require.Equal(t, []*entity.Currency{&testAZN}, cs)
+
+		cs, err = repo.GetLazy(ctx, -1, "")
+		require.Nil(t, err)
+		require.Empty(t, cs)
+	})
+	t.Run("concurrent access", func(t *testing.T) {
+		wg := sync.WaitGroup{}
+		for i := 0; i < 10; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				require.Nil(t, repo.SetAll(ctx, []*entity.Currency{&testAZN, &testUSD}))
+			}()
+			go func() {
+				defer wg.Done()
+				_, err := repo.GetPage(ctx, 10, 0)
+				require.Nil(t, err)
+			}()
+		}
+		wg.Wait()
+	})
+}
@@ -0,0 +1,141 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+
+	"github.com/redselig/currencier/internal/domain/usecase"
+	"github.com/redselig/currencier/internal/util"
+)
+
+const ErrSpec = "can't parse schedule %v"
+
+// Job is the work run by the schedule.
+type Job func(ctx context.Context) error
+
+// Config sets when the job runs.
+type Config struct {
+	// Spec is a standard 5 field cron expression or a descriptor like @daily or @every 1h,
+	// it may set its own time zone with CRON_TZ=Europe/Moscow prefix.
+	Spec string
+	// Location is the time zone the Spec is evaluated in, UTC by default.
+	Location *time.Location
+	// RetryEvery repeats the failed job until it succeeds or the next run is due, 0 disables retries.
+	RetryEvery time.Duration
+	// RunOnStart runs the job once as soon as the scheduler starts.
+	RunOnStart bool
+}
+
+// Scheduler runs a job on a cron schedule and retries its failures in between the runs.
+type Scheduler struct {
+	name     string
+	schedule cron.Schedule
+	location *time.Location
+	retry    time.Duration
+	onStart  bool
+	job      Job
+	logger   usecase.Logger
+
+	mu      sync.Mutex
+	started time.Time
+}
+
+// NewScheduler creates scheduler of the job, name is used in logs.
+func NewScheduler(name string, cfg Config, job Job, logger usecase.Logger) (*Scheduler, error) {
+	schedule, err := cron.ParseStandard(cfg.Spec)
+	if err != nil {
+		return nil, errors.Wrapf(err, ErrSpec, cfg.Spec)
+	}
+	location := cfg.Location
+	if location == nil {
+		location = time.UTC
+	}
+	return &Scheduler{
+		name:     name,
+		schedule: schedule,
+		location: location,
+		retry:    cfg.RetryEvery,
+		onStart:  cfg.RunOnStart,
+		job:      job,
+		logger:   logger,
+	}, nil
+}
+
+// Next returns the time of the next scheduled run after t.
+func (s *Scheduler) Next(t time.Time) time.Time {
+	return s.schedule.Next(t.In(s.location))
+}
+
+// Run runs the job on the schedule until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	defer s.logger.Info(ctx, "scheduler stopped", usecase.String("job", s.name))
+
+	if s.onStart {
+		s.run(ctx, s.Next(time.Now()))
+	}
+	for {
+		next := s.Next(time.Now())
+		s.logger.Info(ctx, "job is scheduled", usecase.String("job", s.name), usecase.Time("at", next))
+		if !sleepUntil(ctx, next) {
+			return
+		}
+		s.run(ctx, s.Next(next))
+	}
+}
+
+// RunningSince returns when the running job started, zero time if the scheduler is waiting for the next run.
+func (s *Scheduler) RunningSince() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.started
+}
+
+// run runs the job and retries it until it succeeds or the deadline of the next run comes,
+// every try has its own request id to tell it in logs.
+func (s *Scheduler) run(ctx context.Context, deadline time.Time) {
+	for {
+		jobCtx := util.WithRequestID(ctx, "")
+		err := s.runJob(jobCtx)
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+		retryAt := time.Now().Add(s.retry)
+		if s.retry <= 0 || !retryAt.Before(deadline) {
+			s.logger.Error(jobCtx, "job failed", usecase.String("job", s.name), usecase.Err(err))
+			return
+		}
+		s.logger.Warn(jobCtx, "job failed, retrying", usecase.String("job", s.name),
+			usecase.Duration("retry_in", s.retry), usecase.Err(err))
+		if !sleepUntil(ctx, retryAt) {
+			return
+		}
+	}
+}
+
+func (s *Scheduler) runJob(ctx context.Context) error {
+	s.mu.Lock()
+	s.started = time.Now()
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.started = time.Time{}
+		s.mu.Unlock()
+	}()
+	return s.job(ctx)
+}
+
+// sleepUntil waits for t and returns false if ctx is done earlier.
+func sleepUntil(ctx context.Context, t time.Time) bool {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/redselig/currencier/internal/mocks"
+	"github.com/redselig/currencier/internal/util"
+)
+
+func TestScheduler_Next(t *testing.T) {
+	msk, err := time.LoadLocation("Europe/Moscow")
+	require.Nil(t, err)
+	friday := time.Date(2020, 3, 6, 9, 0, 0, 0, time.UTC) // 12:00 in Moscow
+
+	tCases := []struct {
+		title string
+		cfg   Config
+		next  time.Time
+	}{
+		{"weekdays in location",
+			Config{Spec: "35 11 * * 1-5", Location: msk},
+			time.Date(2020, 3, 9, 11, 35, 0, 0, msk),
+		},
+		{"time zone in spec",
+			Config{Spec: "CRON_TZ=Europe/Moscow 35 11 * * *"},
+			time.Date(2020, 3, 7, 11, 35, 0, 0, msk),
+		},
+		{"utc by default",
+			Config{Spec: "35 11 * * *"},
+			time.Date(2020, 3, 6, 11, 35, 0, 0, time.UTC),
+		},
+		{"interval",
+			Config{Spec: "@every 5m"},
+			friday.Add(5 * time.Minute),
+		},
+	}
+	for _, tcase := range tCases {
+		t.Run(tcase.title, func(t *testing.T) {
+			s, err := NewScheduler("test", tcase.cfg, nil, mocks.NewMockLogger())
+			require.Nil(t, err)
+			require.True(t, tcase.next.Equal(s.Next(friday)), "next run %v", s.Next(friday))
+		})
+	}
+
+	_, err = NewScheduler("test", Config{Spec: "every day"}, nil, mocks.NewMockLogger())
+	require.NotNil(t, err)
+}
+
+func TestScheduler_Run(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var calls int
+	ids := map[string]bool{}
+	job := func(ctx context.Context) error {
+		calls++
+		ids[util.GetRequestID(ctx)] = true
+		if calls < 3 {
+			return errors.New("no rates yet")
+		}
+		cancel()
+		return nil
+	}
+	s, err := NewScheduler("test", Config{Spec: "@every 1h", RetryEvery: 10 * time.Millisecond, RunOnStart: true},
+		job, mocks.NewMockLogger())
+	require.Nil(t, err)
+
+	s.Run(ctx)
+	require.Equal(t, 3, calls)
+	require.Len(t, ids, 3)
+	require.False(t, ids[""])
+	require.Equal(t, context.Canceled, ctx.Err())
+}
+
+func TestScheduler_RunningSince(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var s *Scheduler
+	var since time.Time
+	job := func(ctx context.Context) error {
+		since = s.RunningSince()
+		cancel()
+		return nil
+	}
+	s, err := NewScheduler("test", Config{Spec: "@every 1h", RunOnStart: true}, job, mocks.NewMockLogger())
+	require.Nil(t, err)
+	require.True(t, s.RunningSince().IsZero())
+
+	start := time.Now()
+	s.Run(ctx)
+	require.False(t, since.Before(start))
+	require.True(t, s.RunningSince().IsZero())
+}
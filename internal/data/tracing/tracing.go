@@ -0,0 +1,103 @@
+package tracing
+
+import (
+	"context"
+	"os"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/redselig/currencier/internal/domain/entity"
+	"github.com/redselig/currencier/internal/util"
+)
+
+const (
+	ExporterNone   = ""
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+
+	ServiceName = "currencier"
+
+	// RequestIDKey is the attribute the request id is attached to spans by.
+	RequestIDKey = attribute.Key("request.id")
+)
+
+// Config sets where spans are exported to.
+type Config struct {
+	// Exporter is otlp, stdout or empty to disable tracing.
+	Exporter string
+	// Endpoint is host:port of the OTLP/HTTP collector, localhost:4318 by default.
+	Endpoint string
+	// Insecure disables TLS to the collector.
+	Insecure bool
+	// SampleRatio is the share of the traces started by the service which are sampled,
+	// the sampling decision of the caller is respected anyway.
+	SampleRatio float64
+}
+
+// Setup installs the global tracer provider exporting spans as configured and the W3C trace context propagator,
+// the returned shutdown flushes the spans left.
+func Setup(ctx context.Context, cfg Config) (shutdown func(ctx context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case ExporterNone:
+		return func(ctx context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		opts := []otlptracehttp.Option{}
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, errors.Errorf("unknown tracing exporter %v", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't create %v tracing exporter", cfg.Exporter)
+	}
+
+	ratio := cfg.SampleRatio
+	if ratio <= 0 {
+		ratio = 1
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts span by the tracer of the package, the request id of ctx is attached to it.
+func Start(ctx context.Context, tracer, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	ctx, span := otel.Tracer(tracer).Start(ctx, name, opts...)
+	if id := util.GetRequestID(ctx); id != "" {
+		span.SetAttributes(RequestIDKey.String(id))
+	}
+	return ctx, span
+}
+
+// End records the error of the traced operation and ends its span, not found isn't an error of the operation.
+func End(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, entity.ErrNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
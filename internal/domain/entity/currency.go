@@ -1,22 +1,67 @@
 package entity
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
 
+	"github.com/shopspring/decimal"
+)
+
+// ErrNotFound is returned by repositories when there is no currency or rate looked for.
+var ErrNotFound = errors.New("not found")
+
+// Currency is the price (Value) of Nominal units of the currency in the Base currency.
 type Currency struct {
 	ID       string
 	NumCode  int
 	CharCode string
 	Nominal  int
 	Name     string
-	Value    float64
+	Value    decimal.Decimal
+	Base     string
+	Date     time.Time
+	Market   string
+	Provider string
+}
+
+// RateDate returns the official day the rate is set on, today if the source didn't report it.
+func (c *Currency) RateDate() time.Time {
+	if c.Date.IsZero() {
+		return Day(time.Now().UTC())
+	}
+	return Day(c.Date)
+}
+
+// Day truncates the time to the beginning of its day in UTC keeping the calendar date,
+// the rates are set on days rather than moments.
+func Day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Conversion is the Amount of From currency expressed in To currency at the Rate set on the Date.
+type Conversion struct {
+	From   string
+	To     string
+	Amount decimal.Decimal
+	Rate   decimal.Decimal
+	Result decimal.Decimal
+	Date   time.Time
 }
 
 type CurrencyInternalRepository interface {
 	GetByID(ctx context.Context, id string) (*Currency, error)
+	GetByCharCode(ctx context.Context, code string) (*Currency, error)
+	GetByNumCode(ctx context.Context, code int) (*Currency, error)
+	// GetNewestDate returns the date of the newest of the latest rates, zero time if there are none.
+	GetNewestDate(ctx context.Context) (time.Time, error)
+	GetByIDOnDate(ctx context.Context, id string, date time.Time) (*Currency, error)
+	GetHistory(ctx context.Context, id string, from, to time.Time) ([]*Currency, error)
 	GetPage(ctx context.Context, limit, offset int) ([]*Currency, error)
 	GetLazy(ctx context.Context, limit int, lastID string) ([]*Currency, error)
 	SetAll(ctx context.Context, cs []*Currency) error
 }
 type CurrencyExternalRepository interface {
 	Load(context.Context) ([]*Currency, error)
+	LoadOnDate(ctx context.Context, date time.Time) ([]*Currency, error)
 }
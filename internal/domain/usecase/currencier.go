@@ -2,12 +2,20 @@ package usecase
 
 import (
 	"context"
+	"time"
+
+	"github.com/shopspring/decimal"
+
 	"github.com/redselig/currencier/internal/domain/entity"
 )
 
 type Currencier interface {
 	UpdateCurrencies(ctx context.Context) error
+	UpdateCurrenciesOnDate(ctx context.Context, date time.Time) error
 	GetCurrencyBuID(ctx context.Context, id string) (*entity.Currency, error)
-	GetCurrenciesPage(ctx context.Context, limit, offset int) ([]*entity.Currency, error)
-	GetCurrenciesLazy(ctx context.Context, limit int, lastID string) ([]*entity.Currency, error)
+	GetCurrencyOnDate(ctx context.Context, id string, date time.Time) (*entity.Currency, error)
+	GetCurrencyHistory(ctx context.Context, id string, from, to time.Time) ([]*entity.Currency, error)
+	Convert(ctx context.Context, from, to string, amount decimal.Decimal, date time.Time) (*entity.Conversion, error)
+	GetCurrenciesPage(ctx context.Context, limit, offset int, base string) ([]*entity.Currency, error)
+	GetCurrenciesLazy(ctx context.Context, limit int, lastID, base string) ([]*entity.Currency, error)
 }
@@ -2,64 +2,444 @@ package usecase
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
 
 	"github.com/redselig/currencier/internal/domain/entity"
 )
 
 const (
-	ErrLoad   = "can't load currencies"
-	ErrGet    = "can't get currency by id"
-	ErrGetAll = "can't get currencies"
+	ErrLoad     = "can't load currencies"
+	ErrNoProv   = "no rates providers"
+	ErrEmpty    = "provider returned no rates"
+	ErrGet      = "can't get currency by id"
+	ErrGetAll   = "can't get currencies"
+	ErrGetHis   = "can't get currency history"
+	ErrConv     = "can't convert currency"
+	ErrUnkn     = "unknown currency %v"
+	ErrBase     = "no rate to rebase %v to %v"
+	ErrBaseCode = "base must be ISO char code"
+	ErrNoRate   = "no rate of %v on %v"
+	ErrZeroRate = "zero rate of %v to %v"
+
+	// BaseCurrency is the currency the rates are set to if the provider doesn't tell another one.
+	BaseCurrency = "RUB"
+	// DefaultScale is the number of decimal places the CBR publishes rates with.
+	DefaultScale = 4
+
+	// storedPage is the page size the stored rates are read by to compare them with the loaded ones.
+	storedPage = 500
 )
 
 var _ Currencier = (*CurrencierInteractor)(nil)
 
 type CurrencierInteractor struct {
-	extRepo entity.CurrencyExternalRepository
-	intRepo entity.CurrencyInternalRepository
+	extRepos []entity.CurrencyExternalRepository
+	intRepo  entity.CurrencyInternalRepository
+	scale    int32
+	logger   Logger
 }
 
-func NewCurrencierInteractor(extRepo entity.CurrencyExternalRepository, intRepo entity.CurrencyInternalRepository) *CurrencierInteractor {
+// NewCurrencierInteractor creates interactor loading rates from the first working of extRepos in their order
+// and rounding calculated rates and amounts to scale decimal places.
+func NewCurrencierInteractor(extRepos []entity.CurrencyExternalRepository, intRepo entity.CurrencyInternalRepository, scale int32, logger Logger) *CurrencierInteractor {
 	return &CurrencierInteractor{
-		extRepo: extRepo,
-		intRepo: intRepo,
+		extRepos: extRepos,
+		intRepo:  intRepo,
+		scale:    scale,
+		logger:   logger,
 	}
 }
 
+// UpdateCurrencies stores the latest rates, the write is skipped if all of them are already stored as they are.
 func (c *CurrencierInteractor) UpdateCurrencies(ctx context.Context) error {
-	cs, err := c.extRepo.Load(ctx)
-	if err != nil {
-		return errors.Wrap(err, ErrLoad)
+	return c.update(ctx, true, func(repo entity.CurrencyExternalRepository) ([]*entity.Currency, error) {
+		return repo.Load(ctx)
+	})
+}
+
+func (c *CurrencierInteractor) UpdateCurrenciesOnDate(ctx context.Context, date time.Time) error {
+	return c.update(ctx, false, func(repo entity.CurrencyExternalRepository) ([]*entity.Currency, error) {
+		return repo.LoadOnDate(ctx, date)
+	})
+}
+
+// update stores rates of the first provider which loads them, failures of the previous ones are logged.
+// Unchanged rates are not stored again if skipUnchanged is set.
+func (c *CurrencierInteractor) update(ctx context.Context, skipUnchanged bool, load func(repo entity.CurrencyExternalRepository) ([]*entity.Currency, error)) error {
+	err := errors.New(ErrNoProv)
+	for i, repo := range c.extRepos {
+		var cs []*entity.Currency
+		cs, err = load(repo)
+		if err == nil && len(cs) == 0 {
+			err = errors.New(ErrEmpty)
+		}
+		if err != nil {
+			if i < len(c.extRepos)-1 {
+				c.logger.Warn(ctx, "rates provider failed, fall back to the next one", Err(err))
+			}
+			continue
+		}
+		for _, cr := range cs {
+			if cr.Base == "" {
+				cr.Base = BaseCurrency
+			}
+		}
+		if skipUnchanged && c.unchanged(ctx, cs) {
+			c.logger.Info(ctx, "rates are not changed, skip saving", String("date", feedDate(cs).Format("2006-01-02")))
+			return nil
+		}
+		if err = c.intRepo.SetAll(ctx, cs); err != nil {
+			return unavailable(err, ErrLoad)
+		}
+		return nil
 	}
-	err = c.intRepo.SetAll(ctx, cs)
+	return unavailable(err, ErrLoad)
+}
+
+// unchanged reports whether all the rates are stored as they are, the stored rates are compared
+// instead of the previously written ones so restarted and other replicas skip them too.
+func (c *CurrencierInteractor) unchanged(ctx context.Context, cs []*entity.Currency) bool {
+	stored, err := c.stored(ctx)
 	if err != nil {
-		return errors.Wrap(err, ErrLoad)
+		c.logger.Warn(ctx, "can't compare rates with the stored ones", Err(err))
+		return false
+	}
+	for _, cr := range cs {
+		if prev, ok := stored[cr.ID]; !ok || !sameRate(cr, prev) {
+			return false
+		}
+	}
+	return true
+}
+
+// stored returns all the latest rates by their ids.
+func (c *CurrencierInteractor) stored(ctx context.Context) (map[string]*entity.Currency, error) {
+	stored := map[string]*entity.Currency{}
+	for offset := 0; ; offset += storedPage {
+		cs, err := c.intRepo.GetPage(ctx, storedPage, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, cr := range cs {
+			stored[cr.ID] = cr
+		}
+		if len(cs) < storedPage {
+			return stored, nil
+		}
 	}
-	return nil
 }
 
+// sameRate reports whether the loaded rate is stored as it is, the repositories store rates
+// without nominal as set for one unit and rates without date as set on the day they are stored.
+func sameRate(cr, stored *entity.Currency) bool {
+	nominal := cr.Nominal
+	if nominal < 1 {
+		nominal = 1
+	}
+	return cr.ID == stored.ID && cr.NumCode == stored.NumCode && cr.CharCode == stored.CharCode &&
+		nominal == stored.Nominal && cr.Name == stored.Name && cr.Value.Equal(stored.Value) &&
+		cr.Base == stored.Base && cr.Provider == stored.Provider && cr.RateDate().Equal(stored.Date)
+}
+
+// feedDate returns the latest date the rates are set on.
+func feedDate(cs []*entity.Currency) time.Time {
+	var date time.Time
+	for _, cr := range cs {
+		if cr.Date.After(date) {
+			date = cr.Date
+		}
+	}
+	return date
+}
+
+// GetCurrencyBuID returns the latest rate of the currency by CBR id, ISO char code or ISO num code.
 func (c *CurrencierInteractor) GetCurrencyBuID(ctx context.Context, id string) (*entity.Currency, error) {
-	cr, err := c.intRepo.GetByID(ctx, id)
+	cr, err := c.getLatest(ctx, id)
+	if errors.Is(err, entity.ErrNotFound) {
+		return nil, unknown(id)
+	}
 	if err != nil {
-		return nil, errors.Wrap(err, ErrGet)
+		return nil, unavailable(err, ErrGet)
 	}
 	return cr, nil
 }
 
-func (c *CurrencierInteractor) GetCurrenciesLazy(ctx context.Context, limit int, lastID string) ([]*entity.Currency, error) {
+func (c *CurrencierInteractor) GetCurrencyOnDate(ctx context.Context, id string, date time.Time) (*entity.Currency, error) {
+	id, err := c.resolveID(ctx, id)
+	if err != nil {
+		return nil, unavailable(err, ErrGet)
+	}
+	cr, err := c.intRepo.GetByIDOnDate(ctx, id, date)
+	if errors.Is(err, entity.ErrNotFound) {
+		return nil, NewError(KindNotFound, err, fmt.Sprintf(ErrNoRate, id, date.Format("2006-01-02"))).
+			WithDetail("currency", id)
+	}
+	if err != nil {
+		return nil, unavailable(err, ErrGet)
+	}
+	return cr, nil
+}
+
+func (c *CurrencierInteractor) GetCurrencyHistory(ctx context.Context, id string, from, to time.Time) ([]*entity.Currency, error) {
+	id, err := c.resolveID(ctx, id)
+	if err != nil {
+		return nil, unavailable(err, ErrGetHis)
+	}
+	cs, err := c.intRepo.GetHistory(ctx, id, from, to)
+	if err != nil {
+		return nil, unavailable(err, ErrGetHis)
+	}
+	return cs, nil
+}
+
+func (c *CurrencierInteractor) GetCurrenciesLazy(ctx context.Context, limit int, lastID, base string) ([]*entity.Currency, error) {
 	cs, err := c.intRepo.GetLazy(ctx, limit, lastID)
 	if err != nil {
-		return nil, errors.Wrap(err, ErrGetAll)
+		return nil, unavailable(err, ErrGetAll)
+	}
+	if cs, err = c.rebase(ctx, cs, base); err != nil {
+		return nil, unavailable(err, ErrGetAll)
 	}
 	return cs, nil
 }
 
-func (c *CurrencierInteractor) GetCurrenciesPage(ctx context.Context, limit, offset int) ([]*entity.Currency, error) {
+func (c *CurrencierInteractor) GetCurrenciesPage(ctx context.Context, limit, offset int, base string) ([]*entity.Currency, error) {
 	cs, err := c.intRepo.GetPage(ctx, limit, offset)
 	if err != nil {
-		return nil, errors.Wrap(err, ErrGetAll)
+		return nil, unavailable(err, ErrGetAll)
+	}
+	if cs, err = c.rebase(ctx, cs, base); err != nil {
+		return nil, unavailable(err, ErrGetAll)
 	}
 	return cs, nil
 }
+
+// rebase returns copies of the rates set to base, the rates are returned as stored if base is empty.
+func (c *CurrencierInteractor) rebase(ctx context.Context, cs []*entity.Currency, base string) ([]*entity.Currency, error) {
+	if base == "" {
+		return cs, nil
+	}
+	if !isCharCode(base) {
+		return nil, NewError(KindInvalid, nil, ErrBaseCode).WithDetail("base", base)
+	}
+	base = strings.ToUpper(base)
+	prices := map[string]decimal.Decimal{}
+	rebased := make([]*entity.Currency, 0, len(cs))
+	for _, cr := range cs {
+		from := baseOf(cr)
+		price, ok := prices[from]
+		if !ok {
+			var err error
+			if price, _, err = c.crossRate(ctx, from, base, time.Time{}); err != nil {
+				return nil, err
+			}
+			prices[from] = price
+		}
+		r := *cr
+		if from != base {
+			r.Value = cr.Value.Mul(price).Round(c.scale)
+		}
+		r.Base = base
+		rebased = append(rebased, &r)
+	}
+	return rebased, nil
+}
+
+// Convert calculates amount of from currency in to currency, rates set to different bases are crossed
+// through each other, the latest rates are used if date is zero.
+func (c *CurrencierInteractor) Convert(ctx context.Context, from, to string, amount decimal.Decimal, date time.Time) (*entity.Conversion, error) {
+	base := to
+	if !isCharCode(to) {
+		cr, err := c.getLatest(ctx, to)
+		if errors.Is(err, entity.ErrNotFound) {
+			return nil, unknown(to)
+		}
+		if err != nil {
+			return nil, unavailable(err, ErrConv)
+		}
+		base = cr.CharCode
+	}
+	rate, rateDate, err := c.price(ctx, from, strings.ToUpper(base), date)
+	if err != nil {
+		return nil, unavailable(err, ErrConv)
+	}
+	return &entity.Conversion{
+		From:   from,
+		To:     to,
+		Amount: amount,
+		Rate:   rate.Round(c.scale),
+		Result: amount.Mul(rate).Round(c.scale),
+		Date:   rateDate,
+	}, nil
+}
+
+// price returns the price of one unit of the currency in base and the date it's set on.
+func (c *CurrencierInteractor) price(ctx context.Context, code, base string, date time.Time) (decimal.Decimal, time.Time, error) {
+	if strings.EqualFold(code, base) {
+		return decimal.New(1, 0), date, nil
+	}
+	cr, err := c.rate(ctx, code, date)
+	if err != nil {
+		return decimal.Zero, date, err
+	}
+	if cr == nil {
+		// base currencies have no rates of their own, so they are priced by the rates set to them
+		price, crossDate, err := c.crossRate(ctx, strings.ToUpper(code), base, date)
+		if err != nil && AsError(err).Kind == KindNotFound {
+			return decimal.Zero, date, unknown(code)
+		}
+		if err != nil {
+			return decimal.Zero, date, err
+		}
+		return price, crossDate, nil
+	}
+	price, crossDate, err := c.crossRate(ctx, baseOf(cr), base, date)
+	if err != nil {
+		return decimal.Zero, date, err
+	}
+	if crossDate.After(cr.Date) {
+		return unitValue(cr).Mul(price), crossDate, nil
+	}
+	return unitValue(cr).Mul(price), cr.Date, nil
+}
+
+// crossRate returns the price of one unit of from base currency in to base currency by the rate of one of them
+// set to the other or by their rates set to a common base.
+func (c *CurrencierInteractor) crossRate(ctx context.Context, from, to string, date time.Time) (decimal.Decimal, time.Time, error) {
+	if from == to {
+		return decimal.New(1, 0), time.Time{}, nil
+	}
+	fromRate, err := c.rate(ctx, from, date)
+	if err != nil {
+		return decimal.Zero, date, err
+	}
+	if fromRate != nil && baseOf(fromRate) == to {
+		return unitValue(fromRate), fromRate.Date, nil
+	}
+	toRate, err := c.rate(ctx, to, date)
+	if err != nil {
+		return decimal.Zero, date, err
+	}
+	if toRate != nil && baseOf(toRate) == from {
+		if err := divisible(toRate); err != nil {
+			return decimal.Zero, date, err
+		}
+		return decimal.New(1, 0).Div(unitValue(toRate)), toRate.Date, nil
+	}
+	if fromRate != nil && toRate != nil && baseOf(fromRate) == baseOf(toRate) {
+		if err := divisible(toRate); err != nil {
+			return decimal.Zero, date, err
+		}
+		crossDate := fromRate.Date
+		if toRate.Date.After(crossDate) {
+			crossDate = toRate.Date
+		}
+		return unitValue(fromRate).Div(unitValue(toRate)), crossDate, nil
+	}
+	return decimal.Zero, date, NewError(KindNotFound, nil, fmt.Sprintf(ErrBase, from, to))
+}
+
+// rate returns the rate of the currency on the date or the latest one if date is zero, nil if there is no rate.
+func (c *CurrencierInteractor) rate(ctx context.Context, code string, date time.Time) (*entity.Currency, error) {
+	cr, err := c.getLatest(ctx, code)
+	if errors.Is(err, entity.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, unavailable(err, ErrGet)
+	}
+	if date.IsZero() {
+		return cr, nil
+	}
+	cr, err = c.intRepo.GetByIDOnDate(ctx, cr.ID, date)
+	if errors.Is(err, entity.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, unavailable(err, ErrGet)
+	}
+	return cr, nil
+}
+
+// unitValue returns the price of one unit of the currency in its base.
+func unitValue(cr *entity.Currency) decimal.Decimal {
+	if cr.Nominal > 1 {
+		return cr.Value.Div(decimal.New(int64(cr.Nominal), 0))
+	}
+	return cr.Value
+}
+
+// divisible returns error if the rate can't be divided by as the provider stored a zero rate.
+func divisible(cr *entity.Currency) error {
+	if !cr.Value.IsZero() {
+		return nil
+	}
+	return NewError(KindUnavailable, nil, fmt.Sprintf(ErrZeroRate, cr.CharCode, baseOf(cr))).WithDetail("currency", cr.CharCode)
+}
+
+// baseOf returns the currency the rate is set to, rates stored before bases were introduced are set to BaseCurrency.
+func baseOf(cr *entity.Currency) string {
+	if cr.Base == "" {
+		return BaseCurrency
+	}
+	return cr.Base
+}
+
+// getLatest finds the latest rate of the currency by CBR id (R01235), ISO char code (USD) or ISO num code (840).
+// Other numbers are not found as the rates without num code are stored with zero one.
+func (c *CurrencierInteractor) getLatest(ctx context.Context, code string) (*entity.Currency, error) {
+	if num, err := strconv.Atoi(code); err == nil {
+		if !isNumCode(code) {
+			return nil, entity.ErrNotFound
+		}
+		return c.intRepo.GetByNumCode(ctx, num)
+	}
+	if isCharCode(code) {
+		return c.intRepo.GetByCharCode(ctx, strings.ToUpper(code))
+	}
+	return c.intRepo.GetByID(ctx, code)
+}
+
+// resolveID turns the code to the id the rates are stored by, the currency is unknown if it has no latest rate.
+func (c *CurrencierInteractor) resolveID(ctx context.Context, code string) (string, error) {
+	cr, err := c.getLatest(ctx, code)
+	if errors.Is(err, entity.ErrNotFound) {
+		return "", unknown(code)
+	}
+	if err != nil {
+		return "", err
+	}
+	return cr.ID, nil
+}
+
+// isNumCode reports whether the code is ISO num code: three digits, not all of them zero.
+func isNumCode(code string) bool {
+	if len(code) != 3 || code == "000" {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isCharCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,189 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redselig/currencier/internal/domain/entity"
+	"github.com/redselig/currencier/internal/domain/usecase"
+	"github.com/redselig/currencier/internal/mocks"
+)
+
+var testCurrency = entity.Currency{
+	ID:       "R01235",
+	CharCode: "USD",
+	Nominal:  1,
+	Name:     "Доллар США",
+	Provider: "json",
+}
+
+func TestCurrencierInteractor_UpdateCurrencies(t *testing.T) {
+	ctx := context.Background()
+	failed := mocks.NewMockExternalRepo(nil, errors.New("cbr is down"))
+	empty := mocks.NewMockExternalRepo(nil, nil)
+	working := mocks.NewMockExternalRepo([]*entity.Currency{&testCurrency}, nil)
+
+	t.Run("fall back to the next provider", func(t *testing.T) {
+		repo := mocks.NewFakeRepo()
+		currencier := usecase.NewCurrencierInteractor([]entity.CurrencyExternalRepository{failed, empty, working},
+			repo, usecase.DefaultScale, mocks.NewMockLogger())
+
+		require.Nil(t, currencier.UpdateCurrencies(ctx))
+		c, err := repo.GetByID(ctx, testCurrency.ID)
+		require.Nil(t, err)
+		require.Equal(t, "json", c.Provider)
+	})
+	t.Run("all providers failed", func(t *testing.T) {
+		currencier := usecase.NewCurrencierInteractor([]entity.CurrencyExternalRepository{empty, failed},
+			mocks.NewFakeRepo(), usecase.DefaultScale, mocks.NewMockLogger())
+
+		err := currencier.UpdateCurrencies(ctx)
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "cbr is down")
+	})
+}
+
+func TestCurrencierInteractor_Rebase(t *testing.T) {
+	ctx := context.Background()
+	repo := mocks.NewFakeRepo()
+	require.Nil(t, repo.SetAll(ctx, []*entity.Currency{
+		{ID: "R01235", CharCode: "USD", Nominal: 1, Value: decimal.RequireFromString("75"), Base: "RUB"},
+		{ID: "R01239", CharCode: "EUR", Nominal: 1, Value: decimal.RequireFromString("90"), Base: "RUB"},
+		{ID: "JPY", CharCode: "JPY", Nominal: 1000, Value: decimal.RequireFromString("8"), Base: "EUR"},
+	}))
+	currencier := usecase.NewCurrencierInteractor(nil, repo, usecase.DefaultScale, mocks.NewMockLogger())
+
+	t.Run("rates as stored", func(t *testing.T) {
+		cs, err := currencier.GetCurrenciesPage(ctx, 10, 0, "")
+		require.Nil(t, err)
+		require.Len(t, cs, 3)
+		require.Equal(t, "EUR", cs[0].Base)
+		require.Equal(t, "RUB", cs[1].Base)
+	})
+	t.Run("rebase to usd", func(t *testing.T) {
+		cs, err := currencier.GetCurrenciesPage(ctx, 10, 0, "usd")
+		require.Nil(t, err)
+		require.Len(t, cs, 3)
+		require.Equal(t, "9.6", cs[0].Value.String())
+		require.Equal(t, "1", cs[1].Value.String())
+		require.Equal(t, "1.2", cs[2].Value.String())
+		for _, c := range cs {
+			require.Equal(t, "USD", c.Base)
+		}
+	})
+	t.Run("rebase to rub", func(t *testing.T) {
+		cs, err := currencier.GetCurrenciesLazy(ctx, 1, "", "RUB")
+		require.Nil(t, err)
+		require.Len(t, cs, 1)
+		require.Equal(t, "720", cs[0].Value.String())
+	})
+	t.Run("unknown base", func(t *testing.T) {
+		_, err := currencier.GetCurrenciesPage(ctx, 10, 0, "XXX")
+		require.NotNil(t, err)
+	})
+	t.Run("zero rate", func(t *testing.T) {
+		repo := mocks.NewFakeRepo()
+		require.Nil(t, repo.SetAll(ctx, []*entity.Currency{
+			{ID: "R01235", CharCode: "USD", Nominal: 1, Value: decimal.RequireFromString("75"), Base: "RUB"},
+			{ID: "R01239", CharCode: "EUR", Nominal: 1, Value: decimal.Zero, Base: "RUB"},
+		}))
+		currencier := usecase.NewCurrencierInteractor(nil, repo, usecase.DefaultScale, mocks.NewMockLogger())
+
+		_, err := currencier.Convert(ctx, "USD", "EUR", decimal.New(1, 0), time.Time{})
+		require.Equal(t, usecase.KindUnavailable, usecase.AsError(err).Kind)
+		require.Equal(t, "EUR", usecase.AsError(err).Details["currency"])
+
+		_, err = currencier.GetCurrenciesPage(ctx, 10, 0, "EUR")
+		require.Equal(t, usecase.KindUnavailable, usecase.AsError(err).Kind)
+
+		_, err = currencier.Convert(ctx, "RUB", "EUR", decimal.New(1, 0), time.Time{})
+		require.Equal(t, usecase.KindUnavailable, usecase.AsError(err).Kind)
+	})
+	t.Run("convert across bases", func(t *testing.T) {
+		c, err := currencier.Convert(ctx, "JPY", "USD", decimal.New(1000, 0), time.Time{})
+		require.Nil(t, err)
+		require.Equal(t, "9.6", c.Result.String())
+
+		c, err = currencier.Convert(ctx, "RUB", "EUR", decimal.New(180, 0), time.Time{})
+		require.Nil(t, err)
+		require.Equal(t, "2", c.Result.String())
+	})
+}
+
+type countingRepo struct {
+	*mocks.CurrencyFakeRepo
+	writes int
+}
+
+func (r *countingRepo) SetAll(ctx context.Context, cs []*entity.Currency) error {
+	r.writes++
+	return r.CurrencyFakeRepo.SetAll(ctx, cs)
+}
+
+func TestCurrencierInteractor_UpdateUnchanged(t *testing.T) {
+	ctx := context.Background()
+	date := time.Date(2020, 3, 3, 0, 0, 0, 0, time.UTC)
+	rate := entity.Currency{ID: "R01235", CharCode: "USD", Nominal: 1, Value: decimal.RequireFromString("66.3274"), Date: date}
+	repo := &countingRepo{CurrencyFakeRepo: mocks.NewFakeRepo()}
+	feed := []*entity.Currency{&rate}
+	currencier := usecase.NewCurrencierInteractor([]entity.CurrencyExternalRepository{mocks.NewMockExternalRepo(feed, nil)},
+		repo, usecase.DefaultScale, mocks.NewMockLogger())
+
+	require.Nil(t, currencier.UpdateCurrencies(ctx))
+	require.Nil(t, currencier.UpdateCurrencies(ctx))
+	require.Equal(t, 1, repo.writes)
+
+	rate.Value = decimal.RequireFromString("66.5")
+	require.Nil(t, currencier.UpdateCurrencies(ctx))
+	require.Equal(t, 2, repo.writes)
+
+	rate.Date = date.AddDate(0, 0, 1)
+	require.Nil(t, currencier.UpdateCurrencies(ctx))
+	require.Equal(t, 3, repo.writes)
+
+	restarted := usecase.NewCurrencierInteractor([]entity.CurrencyExternalRepository{mocks.NewMockExternalRepo(feed, nil)},
+		repo, usecase.DefaultScale, mocks.NewMockLogger())
+	require.Nil(t, restarted.UpdateCurrencies(ctx))
+	require.Equal(t, 3, repo.writes)
+
+	require.Nil(t, currencier.UpdateCurrenciesOnDate(ctx, date))
+	require.Equal(t, 4, repo.writes)
+}
+
+type failingRepo struct {
+	*mocks.CurrencyFakeRepo
+}
+
+func (r failingRepo) GetPage(ctx context.Context, limit, offset int) ([]*entity.Currency, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestCurrencierInteractor_ErrorKinds(t *testing.T) {
+	ctx := context.Background()
+	currencier := usecase.NewCurrencierInteractor(nil, failingRepo{mocks.NewFakeRepo()}, usecase.DefaultScale, mocks.NewMockLogger())
+
+	_, err := currencier.GetCurrenciesPage(ctx, 10, 0, "")
+	require.Equal(t, usecase.KindUnavailable, usecase.AsError(err).Kind)
+	require.Equal(t, usecase.ErrGetAll, usecase.AsError(err).Message)
+
+	_, err = currencier.Convert(ctx, "USD", "RUB", decimal.New(1, 0), time.Time{})
+	require.Equal(t, usecase.KindNotFound, usecase.AsError(err).Kind)
+
+	c, err := currencier.GetCurrencyBuID(ctx, "R01235")
+	require.Nil(t, c)
+	require.Equal(t, usecase.KindNotFound, usecase.AsError(err).Kind)
+
+	cs, err := currencier.GetCurrencyHistory(ctx, "NOPE", time.Time{}, time.Now())
+	require.Nil(t, cs)
+	require.Equal(t, usecase.KindNotFound, usecase.AsError(err).Kind)
+
+	_, err = currencier.GetCurrenciesLazy(ctx, 10, "", "US")
+	require.Equal(t, usecase.KindInvalid, usecase.AsError(err).Kind)
+
+	require.Equal(t, usecase.KindInternal, usecase.AsError(errors.New("boom")).Kind)
+}
@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Kind is the stable class of a use case error the callers can rely on instead of the message.
+type Kind string
+
+const (
+	KindInvalid     Kind = "invalid_argument"
+	KindNotFound    Kind = "not_found"
+	KindUnavailable Kind = "unavailable"
+	KindInternal    Kind = "internal"
+)
+
+// Error is a use case error, Message and Details are safe to show to the clients unlike the cause.
+type Error struct {
+	Kind    Kind
+	Message string
+	Details map[string]string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NewError creates error of the kind caused by err, err may be nil.
+func NewError(kind Kind, err error, message string) *Error {
+	return &Error{
+		Kind:    kind,
+		Message: message,
+		Err:     err,
+	}
+}
+
+// WithDetail adds a detail of the error shown to the clients.
+func (e *Error) WithDetail(key, value string) *Error {
+	if e.Details == nil {
+		e.Details = map[string]string{}
+	}
+	e.Details[key] = value
+	return e
+}
+
+// AsError returns the use case error in the chain of err,
+// errors not classified by the use cases are internal ones.
+func AsError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return NewError(KindInternal, err, "internal error")
+}
+
+// unavailable wraps failures of the repositories and providers, they are expected to be temporary.
+func unavailable(err error, message string) error {
+	if e := (*Error)(nil); errors.As(err, &e) {
+		return errors.Wrap(err, message)
+	}
+	return NewError(KindUnavailable, err, message)
+}
+
+// unknown is the error of a currency which has no rates.
+func unknown(code string) error {
+	return NewError(KindNotFound, nil, fmt.Sprintf(ErrUnkn, code)).WithDetail("currency", code)
+}
@@ -1,7 +1,49 @@
 package usecase
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
+// Logger writes leveled records of a message and typed fields, the request id of ctx is added to them.
 type Logger interface {
-	Log(ctx context.Context, message interface{}, args ...interface{})
+	Debug(ctx context.Context, msg string, fields ...Field)
+	Info(ctx context.Context, msg string, fields ...Field)
+	Warn(ctx context.Context, msg string, fields ...Field)
+	Error(ctx context.Context, msg string, fields ...Field)
+}
+
+// Field is a key-value of a log record, it's created by the typed constructors below.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+func String(key, value string) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Int(key string, value int) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Int64(key string, value int64) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Bool(key string, value bool) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Time(key string, value time.Time) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Err is the error field of the record, it's keyed by error.
+func Err(err error) Field {
+	return Field{Key: "error", Value: err}
 }
@@ -13,5 +13,15 @@ type MockLogger struct{}
 func NewMockLogger() *MockLogger {
 	return &MockLogger{}
 }
-func (m MockLogger) Log(ctx context.Context, message interface{}, args ...interface{}) {
+
+func (m MockLogger) Debug(ctx context.Context, msg string, fields ...usecase.Field) {
+}
+
+func (m MockLogger) Info(ctx context.Context, msg string, fields ...usecase.Field) {
+}
+
+func (m MockLogger) Warn(ctx context.Context, msg string, fields ...usecase.Field) {
+}
+
+func (m MockLogger) Error(ctx context.Context, msg string, fields ...usecase.Field) {
 }
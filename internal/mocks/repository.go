@@ -2,6 +2,8 @@ package mocks
 
 import (
 	"context"
+	"sort"
+	"time"
 
 	"github.com/redselig/currencier/internal/domain/entity"
 )
@@ -16,6 +18,26 @@ func (c CurrencyInternalRepo) GetByID(ctx context.Context, id string) (*entity.C
 	return c.testCurrency, nil
 }
 
+func (c CurrencyInternalRepo) GetByCharCode(ctx context.Context, code string) (*entity.Currency, error) {
+	return c.testCurrency, nil
+}
+
+func (c CurrencyInternalRepo) GetByNumCode(ctx context.Context, code int) (*entity.Currency, error) {
+	return c.testCurrency, nil
+}
+
+func (c CurrencyInternalRepo) GetNewestDate(ctx context.Context) (time.Time, error) {
+	return c.testCurrency.Date, nil
+}
+
+func (c CurrencyInternalRepo) GetByIDOnDate(ctx context.Context, id string, date time.Time) (*entity.Currency, error) {
+	return c.testCurrency, nil
+}
+
+func (c CurrencyInternalRepo) GetHistory(ctx context.Context, id string, from, to time.Time) ([]*entity.Currency, error) {
+	return []*entity.Currency{c.testCurrency}, nil
+}
+
 func (c CurrencyInternalRepo) GetPage(ctx context.Context, limit, offset int) ([]*entity.Currency, error) {
 	return []*entity.Currency{c.testCurrency}, nil
 }
@@ -33,3 +55,129 @@ func NewMockRepo(currency *entity.Currency) *CurrencyInternalRepo {
 		testCurrency: currency,
 	}
 }
+
+var _ entity.CurrencyInternalRepository = (*CurrencyFakeRepo)(nil)
+
+// CurrencyFakeRepo keeps the latest rates in memory the way the repositories store them,
+// rates on a date are the latest ones if they are set not after it and there is no history.
+type CurrencyFakeRepo struct {
+	latest map[string]*entity.Currency
+}
+
+func (c *CurrencyFakeRepo) GetByID(ctx context.Context, id string) (*entity.Currency, error) {
+	return c.find(func(cr *entity.Currency) bool { return cr.ID == id })
+}
+
+func (c *CurrencyFakeRepo) GetByCharCode(ctx context.Context, code string) (*entity.Currency, error) {
+	return c.find(func(cr *entity.Currency) bool { return cr.CharCode == code })
+}
+
+func (c *CurrencyFakeRepo) GetByNumCode(ctx context.Context, code int) (*entity.Currency, error) {
+	return c.find(func(cr *entity.Currency) bool { return cr.NumCode == code })
+}
+
+func (c *CurrencyFakeRepo) GetNewestDate(ctx context.Context) (time.Time, error) {
+	var newest time.Time
+	for _, cr := range c.latest {
+		if cr.Date.After(newest) {
+			newest = cr.Date
+		}
+	}
+	return newest, nil
+}
+
+func (c *CurrencyFakeRepo) GetByIDOnDate(ctx context.Context, id string, date time.Time) (*entity.Currency, error) {
+	cr, err := c.GetByID(ctx, id)
+	if err == nil && cr.Date.After(date) {
+		return nil, entity.ErrNotFound
+	}
+	return cr, err
+}
+
+func (c *CurrencyFakeRepo) GetHistory(ctx context.Context, id string, from, to time.Time) ([]*entity.Currency, error) {
+	return nil, nil
+}
+
+func (c *CurrencyFakeRepo) GetPage(ctx context.Context, limit, offset int) ([]*entity.Currency, error) {
+	cs := c.sorted()
+	if offset >= len(cs) {
+		return nil, nil
+	}
+	cs = cs[offset:]
+	if limit < len(cs) {
+		cs = cs[:limit]
+	}
+	return cs, nil
+}
+
+func (c *CurrencyFakeRepo) GetLazy(ctx context.Context, limit int, lastID string) ([]*entity.Currency, error) {
+	cs := c.sorted()
+	return c.GetPage(ctx, limit, sort.Search(len(cs), func(i int) bool { return cs[i].ID > lastID }))
+}
+
+func (c *CurrencyFakeRepo) SetAll(ctx context.Context, cs []*entity.Currency) error {
+	for _, row := range cs {
+		cr := *row
+		cr.Date = row.RateDate()
+		if cr.Nominal < 1 {
+			cr.Nominal = 1
+		}
+		if last, ok := c.latest[cr.ID]; !ok || !last.Date.After(cr.Date) {
+			c.latest[cr.ID] = &cr
+		}
+	}
+	return nil
+}
+
+// find returns the freshest rate matching the predicate.
+func (c *CurrencyFakeRepo) find(match func(cr *entity.Currency) bool) (*entity.Currency, error) {
+	var fresh *entity.Currency
+	for _, cr := range c.sorted() {
+		if match(cr) && (fresh == nil || cr.Date.After(fresh.Date)) {
+			fresh = cr
+		}
+	}
+	if fresh == nil {
+		return nil, entity.ErrNotFound
+	}
+	return fresh, nil
+}
+
+// sorted returns copies of the latest rates ordered by id.
+func (c *CurrencyFakeRepo) sorted() []*entity.Currency {
+	cs := make([]*entity.Currency, 0, len(c.latest))
+	for _, cr := range c.latest {
+		cp := *cr
+		cs = append(cs, &cp)
+	}
+	sort.Slice(cs, func(i, j int) bool { return cs[i].ID < cs[j].ID })
+	return cs
+}
+
+func NewFakeRepo() *CurrencyFakeRepo {
+	return &CurrencyFakeRepo{
+		latest: make(map[string]*entity.Currency),
+	}
+}
+
+var _ entity.CurrencyExternalRepository = (*CurrencyExternalRepo)(nil)
+
+type CurrencyExternalRepo struct {
+	testCurrencies []*entity.Currency
+	err            error
+}
+
+func (c CurrencyExternalRepo) Load(ctx context.Context) ([]*entity.Currency, error) {
+	return c.testCurrencies, c.err
+}
+
+func (c CurrencyExternalRepo) LoadOnDate(ctx context.Context, date time.Time) ([]*entity.Currency, error) {
+	return c.testCurrencies, c.err
+}
+
+func NewMockExternalRepo(currencies []*entity.Currency, err error) *CurrencyExternalRepo {
+	return &CurrencyExternalRepo{
+		testCurrencies: currencies,
+		err:            err,
+	}
+}
@@ -2,13 +2,20 @@ package util
 
 import (
 	"context"
+	"strings"
 
 	uuid "github.com/satori/go.uuid"
 )
 
 const (
 	RequestID = contextKey("RequestID")
-	LayoutISO = "2006-01-02 15:04:05"
+	// RequestIDHeader is the header the request id is taken from and returned in.
+	RequestIDHeader = "X-Request-ID"
+	// maxRequestID limits the length of the incoming request id.
+	maxRequestID = 128
+
+	LayoutISO  = "2006-01-02 15:04:05"
+	LayoutDate = "2006-01-02"
 )
 
 type contextKey string
@@ -28,3 +35,29 @@ func SetRequestID(ctx context.Context) context.Context {
 	}
 	return ctx
 }
+
+// WithRequestID returns context with the request id if it's valid, with a new one otherwise,
+// the id of ctx is replaced anyway.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	if !ValidRequestID(id) {
+		id = uuid.NewV4().String()
+	}
+	return context.WithValue(ctx, RequestID, id)
+}
+
+// ValidRequestID reports whether the id is safe to put in logs and headers: it's not empty, not too long
+// and has letters, digits and -_.:/+= only.
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestID {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("-_.:/+=", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
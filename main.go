@@ -16,7 +16,12 @@ limitations under the License.
 package main
 
 import (
+	_ "time/tzdata"
+
+	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/jackc/pgx/v4/stdlib"
+	_ "modernc.org/sqlite"
+
 	"github.com/redselig/currencier/cmd"
 )
 